@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"context"
 	"fmt"
+	"github.com/censoredgit/light/session"
 	"github.com/censoredgit/light/validator"
 	"log/slog"
 	"math"
@@ -18,12 +19,14 @@ import (
 type ActionHandler func(ctx *Ctx) (Response, error)
 
 type Action struct {
-	handler     ActionHandler
-	validator   *RequestValidator
-	middlewares []Middleware
-	name        *string
-	uri         *string
-	isReady     bool
+	handler        ActionHandler
+	validator      *RequestValidator
+	middlewares    []Middleware
+	name           *string
+	uri            *string
+	isReady        bool
+	stateless      bool
+	sessionManager *session.Manager
 }
 
 func (a *Action) Next(ctx *Ctx) (Response, error) {
@@ -87,6 +90,9 @@ func (a *Action) WithMiddleware(m ...Middleware) *Action {
 		if !slices.Contains(a.middlewares, newMiddleware) {
 			a.middlewares = append(a.middlewares, newMiddleware)
 		}
+		if _, ok := newMiddleware.(*StatelessMiddleware); ok {
+			a.stateless = true
+		}
 	}
 
 	slices.SortFunc(a.middlewares, func(a, b Middleware) int {
@@ -96,6 +102,14 @@ func (a *Action) WithMiddleware(m ...Middleware) *Action {
 	return a
 }
 
+// WithSessionManager overrides the session manager for the action. It takes
+// precedence over the group one.
+func (a *Action) WithSessionManager(manager *session.Manager) *Action {
+	a.sessionManager = manager
+
+	return a
+}
+
 func (a *Action) SetValidator(rv *RequestValidator) *Action {
 	a.validator = rv
 
@@ -130,8 +144,15 @@ func (a *Action) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		extras:               make(map[string]string),
 		action:               a,
 		middlewareStackIndex: -1,
+		stateless:            a.stateless,
+		sessionManager:       cmp.Or(a.sessionManager, config.SessionManager),
 	}
 
+	writer := newSessionResponseWriter(res, func() {
+		handleSessionCommit(ctx)
+	})
+	ctx.httpResponse = writer
+
 	if config.Debug {
 		defer func() {
 			if err := req.ParseForm(); err == nil {
@@ -140,23 +161,33 @@ func (a *Action) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		}()
 	}
 
-	ctx.session, err = config.SessionManager.InitByRequest(ctx.request)
-	if err != nil {
-		handleError(ctx, err)
-		return
-	}
 	defer func() {
-		err := config.SessionManager.Close(ctx.Session())
+		writer.commit()
+
+		if !ctx.sessionOpen {
+			return
+		}
+
+		err := ctx.sessionManager.Close(ctx.session)
 		if err != nil {
 			iLogError(fmt.Errorf("close session err: %w", err).Error())
 		}
 	}()
 
-	ctx.flashStorage = NewContextSessionFlashStorage(ctx.session)
-	defer ctx.flashStorage.Flush()
+	if ctx.stateless {
+		ctx.flashStorage = NewContextDummyFlashStorage()
+	} else {
+		ctx.flashStorage = newLazyContextSessionFlashStorage(ctx)
+	}
 
+	handleRemember(ctx)
 	handleUser(ctx)
 
+	if ctx.sessionErr != nil {
+		handleError(ctx, ctx.sessionErr)
+		return
+	}
+
 	err = ctx.parseForm()
 	if err != nil {
 		handleError(ctx, err)
@@ -176,6 +207,9 @@ func (a *Action) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 
 	if response == nil {
 		response, err = ctx.Next()
+		if err == nil {
+			err = ctx.sessionErr
+		}
 		if err != nil {
 			handleError(ctx, err)
 			return
@@ -188,45 +222,70 @@ func (a *Action) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 
 	handleBackAfterAuth(ctx, response)
-	handleCookie(ctx)
 	response.Process(ctx)
 }
 
+func handleSessionCommit(ctx *Ctx) {
+	if ctx.flashStorage != nil {
+		ctx.flashStorage.Flush()
+	}
+
+	if !ctx.sessionOpen {
+		return
+	}
+
+	handleCookie(ctx)
+}
+
 func handleCookie(ctx *Ctx) {
-	if ctx.session.IsNew() {
-		http.SetCookie(ctx.httpResponse, config.SessionManager.ToCookie(ctx.session))
+	cookies, err := ctx.sessionManager.Cookies(ctx.session)
+	if err != nil {
+		iLogError(err.Error())
+	}
+
+	for _, cookie := range cookies {
+		http.SetCookie(ctx.httpResponse, cookie)
 	}
 }
 
 func handleBackAfterAuth(ctx *Ctx, response Response) {
-	if ctx.IsAuth() && ctx.Session().Has(backRedirectKey) {
-		if uri := ctx.Session().Get(backRedirectKey); uri != "" {
+	data := ctx.peekSession()
+	if data == nil {
+		return
+	}
+
+	if ctx.IsAuth() && data.Has(backRedirectKey) {
+		if uri := data.Get(backRedirectKey); uri != "" {
 			response = ctx.RedirectResponse(uri)
 		}
-		ctx.Session().Delete(backRedirectKey)
+		data.Delete(backRedirectKey)
 	}
 }
 
 func handleUser(ctx *Ctx) {
-	if config.UserProvider == nil {
+	if !ctx.IsAuth() {
 		return
 	}
 
-	if !ctx.IsAuth() {
-		return
+	if !loadUser(ctx) {
+		ctx.Logout()
+	}
+}
+
+func loadUser(ctx *Ctx) bool {
+	if config.UserProvider == nil {
+		return true
 	}
 
 	user, err := config.UserProvider.GetAuthIdentification(ctx, ctx.AuthIdentification())
 	if err != nil {
 		iLogError(err.Error())
-		ctx.Logout()
-		return
+		return false
 	}
 
 	if !user.IsActive() {
 		iLogError(fmt.Sprintf("User %s no more active. Logout", ctx.AuthIdentification()))
-		ctx.Logout()
-		return
+		return false
 	}
 
 	role, err := config.UserProvider.GetRoleSupport(ctx, ctx.AuthIdentification())
@@ -235,6 +294,8 @@ func handleUser(ctx *Ctx) {
 	} else {
 		ctx.SetRoleSupport(role)
 	}
+
+	return true
 }
 
 func handleError(ctx *Ctx, err error) {
@@ -1,6 +1,19 @@
 package controller
 
-import "testing"
+import (
+	"bytes"
+	"github.com/censoredgit/light/locker"
+	"github.com/censoredgit/light/session"
+	"github.com/censoredgit/light/session/driver/cookie"
+	"github.com/censoredgit/light/session/driver/memory"
+	"github.com/censoredgit/light/session/hasher"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
 
 func TestActionSuccess(t *testing.T) {
 	c := newController()
@@ -10,3 +23,201 @@ func TestActionSuccess(t *testing.T) {
 		})
 	})
 }
+
+func TestActionCookieSessionFlash(t *testing.T) {
+	setupTestConfig()
+	config.SessionManager = session.MustSetup(&session.Config{
+		Salt:       "test",
+		TTL:        time.Minute,
+		CookieName: "test",
+		Driver:     cookie.Setup(cookie.DefaultCookieName, [][]byte{bytes.Repeat([]byte("k"), 32)}, time.Minute),
+		Hasher:     hasher.Md5Hasher{},
+	})
+
+	store := newTestAction("/store", func(ctx *Ctx) (Response, error) {
+		return ctx.RedirectResponse("/show").With(func(response ResponseExtendData) {
+			response.AddMessage("alert", "saved")
+		}), nil
+	})
+	show := newTestAction("/show", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.InputBag().Get("alert"), http.StatusOK), nil
+	})
+
+	jar := map[string]*http.Cookie{}
+	serve := func(a *Action, req *http.Request) *httptest.ResponseRecorder {
+		for _, c := range jar {
+			req.AddCookie(c)
+		}
+		res := serveTestAction(a, req)
+		for _, c := range res.Result().Cookies() {
+			jar[c.Name] = c
+		}
+		return res
+	}
+
+	serve(store, httptest.NewRequest(http.MethodPost, "/store", nil))
+
+	if res := serve(show, httptest.NewRequest(http.MethodGet, "/show", nil)); res.Body.String() != "saved" {
+		t.Errorf("flash message should be stored in cookie session, got %q", res.Body.String())
+	}
+
+	if res := serve(show, httptest.NewRequest(http.MethodGet, "/show", nil)); res.Body.String() != "" {
+		t.Errorf("flash message should expire after one request, got %q", res.Body.String())
+	}
+}
+
+func TestActionLazySession(t *testing.T) {
+	setupTestConfig()
+
+	plain := newTestAction("/plain", func(ctx *Ctx) (Response, error) {
+		if ctx.IsAuth() {
+			return ctx.CodeResponse(http.StatusForbidden), nil
+		}
+		return ctx.TextResponse("ok", http.StatusOK), nil
+	})
+	store := newTestAction("/store", func(ctx *Ctx) (Response, error) {
+		ctx.Session().Set("test", "stored")
+		return ctx.TextResponse("ok", http.StatusOK), nil
+	})
+	show := newTestAction("/show", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.Session().Get("test"), http.StatusOK), nil
+	})
+
+	res := serveTestAction(plain, httptest.NewRequest(http.MethodGet, "/plain", nil))
+	if res.Code != http.StatusOK || len(res.Result().Cookies()) != 0 {
+		t.Fatalf("untouched session should not be started, got %d %v", res.Code, res.Result().Cookies())
+	}
+
+	res = serveTestAction(store, httptest.NewRequest(http.MethodGet, "/store", nil))
+	cookies := res.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("written session should set cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/show", nil)
+	req.AddCookie(cookies[0])
+	if res = serveTestAction(show, req); res.Body.String() != "stored" {
+		t.Errorf("session should be stored, got %q", res.Body.String())
+	}
+}
+
+func TestActionStateless(t *testing.T) {
+	setupTestConfig()
+
+	a := newTestAction("/hook", func(ctx *Ctx) (Response, error) {
+		ctx.Session().Set("test", "dropped")
+		return ctx.RedirectResponse("/").With(func(response ResponseExtendData) {
+			response.AddMessage("alert", "dropped")
+		}), nil
+	}, Stateless(), Csrf())
+
+	res := serveTestAction(a, httptest.NewRequest(http.MethodPost, "/hook", nil))
+	if res.Code != http.StatusFound {
+		t.Fatalf("csrf should be skipped, got %d", res.Code)
+	}
+	if len(res.Result().Cookies()) != 0 {
+		t.Errorf("stateless route should not set cookies, got %v", res.Result().Cookies())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+	for _, c := range startTestSession() {
+		req.AddCookie(c)
+	}
+	if res = serveTestAction(a, req); len(res.Result().Cookies()) != 0 {
+		t.Errorf("stateless route should ignore existing session, got %v", res.Result().Cookies())
+	}
+}
+
+func TestActionSessionManagerOverride(t *testing.T) {
+	setupTestConfig()
+
+	admin := session.MustSetup(&session.Config{
+		TTL:         time.Minute,
+		CookieName:  "admin",
+		Driver:      memory.Setup(locker.New(&locker.Config{}), time.Minute, time.Minute, memory.DefaultGarbageListInitCap),
+		IdGenerator: session.NewSecureIdGenerator(session.DefaultIdBytes),
+	})
+
+	login := newTestAction("/admin/login", func(ctx *Ctx) (Response, error) {
+		ctx.Login(testUser("admin"))
+		return ctx.CodeResponse(http.StatusOK), nil
+	}).WithSessionManager(admin)
+	adminShow := newTestAction("/admin", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.AuthIdentification(), http.StatusOK), nil
+	}).WithSessionManager(admin)
+	publicShow := newTestAction("/", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.AuthIdentification(), http.StatusOK), nil
+	})
+
+	cookies := serveTestAction(login, httptest.NewRequest(http.MethodPost, "/admin/login", nil)).Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "admin" {
+		t.Fatalf("admin session cookie expected, got %v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.AddCookie(cookies[0])
+	if res := serveTestAction(adminShow, req); res.Body.String() != "admin" {
+		t.Errorf("admin route should use admin session, got %q", res.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	if res := serveTestAction(publicShow, req); res.Body.String() != "" {
+		t.Errorf("public route should not see admin session, got %q", res.Body.String())
+	}
+}
+
+func setupTestConfig() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+	locks := locker.New(&locker.Config{})
+
+	iLog = logger
+	config = Config{
+		Logger:        logger,
+		MaxUploadSize: defaultMaxUploadSize,
+		SessionManager: session.MustSetup(&session.Config{
+			Salt:       "test",
+			TTL:        time.Minute,
+			CookieName: "test",
+			Driver:     memory.Setup(locks, time.Minute, time.Minute, memory.DefaultGarbageListInitCap),
+			Hasher:     hasher.Md5Hasher{},
+			Logger:     logger,
+		}),
+	}
+
+	setupCtx(defaultAuthFieldName, defaultCsrfTokenField)
+	setupCsrfMiddleware(defaultCsrfTokenField)
+	setupLockMiddleware(locks)
+	setupIdempotentMiddleware(NewMemoryResponseStore(), defaultIdempotencyKeysTTL, locks)
+	setupCachePageMiddleware(NewMemoryResponseStore())
+}
+
+func newTestAction(uri string, handler ActionHandler, middlewares ...Middleware) *Action {
+	info := &MountInfo{routeUri: uri}
+
+	a := NewAction(handler).WithMiddleware(middlewares...)
+	a.name = &info.routeName
+	a.uri = &info.routeUri
+	a.middlewares = append(a.middlewares, a)
+	a.isReady = true
+
+	return a
+}
+
+func startTestSession() []*http.Cookie {
+	a := newTestAction("/session", func(ctx *Ctx) (Response, error) {
+		ctx.Session()
+		return ctx.CodeResponse(http.StatusOK), nil
+	})
+
+	return serveTestAction(a, httptest.NewRequest(http.MethodGet, "/session", nil)).Result().Cookies()
+}
+
+func serveTestAction(a *Action, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+
+	return rec
+}
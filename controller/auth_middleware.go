@@ -1,14 +1,14 @@
 package controller
 
-import "errors"
-
 var authMiddlewareCfg struct {
 	loginRouteUri           string
 	loginRouteName          string
 	backAfterAuthQueryParam string
 }
 
-type AuthMiddleware struct{}
+type AuthMiddleware struct {
+	guards []Guard
+}
 
 func setupAuthMiddleware(loginRouteUri, loginRouteName, backAfterAuthQueryParam string) {
 	authMiddlewareCfg.loginRouteUri = loginRouteUri
@@ -16,27 +16,39 @@ func setupAuthMiddleware(loginRouteUri, loginRouteName, backAfterAuthQueryParam
 	authMiddlewareCfg.backAfterAuthQueryParam = backAfterAuthQueryParam
 }
 
-func Auth() *AuthMiddleware {
-	return &AuthMiddleware{}
+func Auth(guards ...Guard) *AuthMiddleware {
+	if len(guards) == 0 {
+		guards = []Guard{BySession()}
+	}
+
+	return &AuthMiddleware{guards: guards}
 }
 
 func (a *AuthMiddleware) Next(ctx *Ctx) (Response, error) {
-	if authMiddlewareCfg.loginRouteUri == "" {
-		return nil, errors.New("login uri required")
-	}
+	for _, guard := range a.guards {
+		authId, err := guard.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-	if ctx.IsGuest() {
-		ref := ctx.Request().Referer()
-		if ref == "" && ctx.Request().RequestURI != authMiddlewareCfg.loginRouteUri {
-			ref = ctx.Request().RequestURI
+		if authId == "" {
+			continue
 		}
-		if ref != "" {
-			ctx.Session().Set(authMiddlewareCfg.backAfterAuthQueryParam, ref)
+
+		if _, isSession := guard.(*SessionGuard); !isSession {
+			ctx.authId = authId
+			if !loadUser(ctx) {
+				ctx.authId = ""
+				continue
+			}
 		}
-		return ctx.RedirectResponse(authMiddlewareCfg.loginRouteUri), nil
+
+		ctx.guard = guard
+
+		return ctx.Next()
 	}
 
-	return ctx.Next()
+	return a.guards[0].Challenge(ctx)
 }
 
 func (a *AuthMiddleware) Priority() uint {
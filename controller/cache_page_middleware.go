@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	pageCacheHeader         = "X-Page-Cache"
+	pageCacheRouteTagPrefix = "route:"
+)
+
+var cachePageMiddlewareCfg struct {
+	store PageCacheStore
+}
+
+func setupCachePageMiddleware(store PageCacheStore) {
+	cachePageMiddlewareCfg.store = store
+}
+
+type CachePageMiddleware struct {
+	ttl    time.Duration
+	varyBy []string
+}
+
+func CachePage(ttl time.Duration, varyBy ...string) *CachePageMiddleware {
+	return &CachePageMiddleware{ttl: ttl, varyBy: varyBy}
+}
+
+func (a *CachePageMiddleware) Next(ctx *Ctx) (Response, error) {
+	if (!ctx.IsGet() && ctx.Request().Method != http.MethodHead) || !ctx.IsGuest() {
+		return ctx.Next()
+	}
+
+	if len(ctx.ErrBag().Errors()) > 0 || len(ctx.InputBag().All()) > 0 {
+		return ctx.Next()
+	}
+
+	key := a.storeKey(ctx)
+
+	stored, found, err := cachePageMiddlewareCfg.store.Get(key)
+	if err != nil {
+		ctx.Log().Error(err.Error())
+	} else if found {
+		return newReplayResponse(stored, map[string]string{pageCacheHeader: "HIT"}, ctx.flashStorage), nil
+	}
+
+	response, err := ctx.Next()
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	return newRecordedResponse(response, func(stored *StoredResponse) {
+		if stored.Code != http.StatusOK || len(stored.Header.Values("Set-Cookie")) > 0 {
+			return
+		}
+
+		// the page may hold per-visitor session data such as the csrf token
+		if ctx.sessionTouched {
+			return
+		}
+
+		if err := cachePageMiddlewareCfg.store.Set(key, stored, a.ttl); err != nil {
+			ctx.Log().Error(err.Error())
+			return
+		}
+
+		tags := ctx.cacheTags
+		if ctx.routeName != "" {
+			tags = append(tags, pageCacheRouteTagPrefix+ctx.routeName)
+		}
+
+		if len(tags) > 0 {
+			if err := cachePageMiddlewareCfg.store.Tag(key, tags...); err != nil {
+				ctx.Log().Error(err.Error())
+			}
+		}
+	}), nil
+}
+
+func (a *CachePageMiddleware) Priority() uint {
+	return 75
+}
+
+func (a *CachePageMiddleware) storeKey(ctx *Ctx) string {
+	key := strings.Builder{}
+	key.WriteString("CachePageMiddleware_")
+	key.WriteString(ctx.Request().Method)
+	key.WriteString(" ")
+	key.WriteString(ctx.Request().URL.String())
+
+	for _, header := range a.varyBy {
+		key.WriteString("\n")
+		key.WriteString(http.CanonicalHeaderKey(header))
+		key.WriteString(": ")
+		key.WriteString(ctx.Request().Header.Get(header))
+	}
+
+	return key.String()
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachePage(t *testing.T) {
+	setupTestConfig()
+
+	renders := 0
+	a := newTestAction("/landing", func(ctx *Ctx) (Response, error) {
+		renders++
+		ctx.CacheTags("landing")
+		return ctx.TextResponse(fmt.Sprintf("render %d", renders), http.StatusOK), nil
+	}, CachePage(time.Minute, "Accept-Language"))
+
+	get := func(lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/landing", nil)
+		req.Header.Set("Accept-Language", lang)
+		return serveTestAction(a, req)
+	}
+
+	if res := get("en"); res.Body.String() != "render 1" || res.Header().Get(pageCacheHeader) != "" {
+		t.Fatalf("first request should render, got %q", res.Body.String())
+	}
+
+	if res := get("en"); res.Body.String() != "render 1" || res.Header().Get(pageCacheHeader) != "HIT" {
+		t.Fatalf("second request should hit cache, got %q", res.Body.String())
+	}
+
+	if res := get("de"); res.Body.String() != "render 2" {
+		t.Fatalf("vary header should split cache, got %q", res.Body.String())
+	}
+
+	ctx := &Ctx{}
+	if err := ctx.InvalidatePageCache("landing"); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := get("en"); res.Body.String() != "render 3" {
+		t.Fatalf("invalidated page should render, got %q", res.Body.String())
+	}
+}
+
+func TestCachePageSkipsUnsafeMethods(t *testing.T) {
+	setupTestConfig()
+
+	renders := 0
+	a := newTestAction("/landing", func(ctx *Ctx) (Response, error) {
+		renders++
+		return ctx.TextResponse("ok", http.StatusOK), nil
+	}, CachePage(time.Minute))
+
+	for range 2 {
+		serveTestAction(a, httptest.NewRequest(http.MethodPost, "/landing", nil))
+	}
+
+	if renders != 2 {
+		t.Errorf("post requests should not be cached, rendered %d times", renders)
+	}
+}
+
+func TestCachePageSkipsSessionPages(t *testing.T) {
+	setupTestConfig()
+
+	a := newTestAction("/form", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.CsrfToken(), http.StatusOK), nil
+	}, CachePage(time.Minute))
+
+	get := func(cookies []*http.Cookie) string {
+		req := httptest.NewRequest(http.MethodGet, "/form", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		return serveTestAction(a, req).Body.String()
+	}
+
+	first, second := startTestSession(), startTestSession()
+
+	firstToken := get(first)
+	if get(first) != firstToken {
+		t.Fatal("csrf token should be kept in session")
+	}
+
+	if secondToken := get(second); secondToken == firstToken {
+		t.Errorf("csrf token of one guest should not be served to another, got %q", secondToken)
+	}
+}
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"github.com/censoredgit/light/locker"
 	"github.com/censoredgit/light/session"
 	"log/slog"
+	"time"
 )
 
 type Config struct {
@@ -24,4 +26,15 @@ type Config struct {
 	StaticPath     string
 	CsrfFieldName  string
 	LoginRouteName string
+
+	IdempotencyStore ResponseStore
+	IdempotencyTTL   time.Duration
+	PageCacheStore   PageCacheStore
+
+	// Locker is used by Lock and Idempotent middlewares, in-memory when nil.
+	Locker locker.SimpleLocker
+
+	RememberTokenProvider RememberTokenProvider
+	RememberCookieName    string
+	RememberTTL           time.Duration
 }
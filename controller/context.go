@@ -54,6 +54,14 @@ type Ctx struct {
 	middlewareStackIndex  int8
 	action                *Action
 	flashStorage          ContextFlashStorage
+	cacheTags             []string
+	authId                string
+	guard                 Guard
+	stateless             bool
+	sessionManager        *session.Manager
+	sessionOpen           bool
+	sessionTouched        bool
+	sessionErr            error
 }
 
 func (ctx *Ctx) Next() (Response, error) {
@@ -132,32 +140,124 @@ func (ctx *Ctx) JsonResponse(data interface{}, code int) *JsonResponse {
 	return newJsonResponse(data, code)
 }
 
+// Session starts the session on first use. Stateless routes and failed
+// starts get a detached session which is never stored.
 func (ctx *Ctx) Session() *session.Data {
+	ctx.sessionTouched = true
+
+	return ctx.openSession()
+}
+
+func (ctx *Ctx) openSession() *session.Data {
+	if ctx.session != nil {
+		return ctx.session
+	}
+
+	if ctx.stateless {
+		ctx.session = session.NewData("", 0)
+		return ctx.session
+	}
+
+	data, err := ctx.sessionManager.InitByRequest(ctx.request)
+	if err != nil {
+		ctx.sessionErr = err
+		ctx.session = session.NewData("", 0)
+		return ctx.session
+	}
+
+	ctx.session = data
+	ctx.sessionOpen = true
+
 	return ctx.session
 }
 
+// peekSession returns the session only if it is started or the request
+// carries one, so reads never create a new session.
+func (ctx *Ctx) peekSession() *session.Data {
+	if ctx.session != nil {
+		return ctx.session
+	}
+
+	if ctx.stateless || !ctx.sessionManager.HasSession(ctx.request) {
+		return nil
+	}
+
+	return ctx.openSession()
+}
+
+func (ctx *Ctx) sessionValue(key string) string {
+	if data := ctx.peekSession(); data != nil {
+		return data.Get(key)
+	}
+
+	return ""
+}
+
+func (ctx *Ctx) IsStateless() bool {
+	return ctx.stateless
+}
+
 func (ctx *Ctx) Log() *slog.Logger {
 	return ctx.log
 }
 
-func (ctx *Ctx) Login(id AuthIdentification) {
-	ctx.session.Set(ctxCfg.authFieldName, id.AuthId())
+func (ctx *Ctx) Login(id AuthIdentification, opts ...LoginOption) {
+	o := &loginOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ctx.Session().Set(ctxCfg.authFieldName, id.AuthId())
+	ctx.Session().SetOwner(id.AuthId(), session.NewMetadata(ctx.request))
+	ctx.regenerateSession()
+
+	if o.remember {
+		if err := issueRememberToken(ctx, id.AuthId()); err != nil {
+			iLogError(err.Error())
+		}
+	}
 }
 
 func (ctx *Ctx) AuthIdentification() string {
-	return ctx.session.Get(ctxCfg.authFieldName)
+	if ctx.authId != "" {
+		return ctx.authId
+	}
+
+	return ctx.sessionValue(ctxCfg.authFieldName)
+}
+
+func (ctx *Ctx) AuthGuard() Guard {
+	return ctx.guard
 }
 
 func (ctx *Ctx) IsAuth() bool {
-	return ctx.session.Has(ctxCfg.authFieldName)
+	return ctx.authId != "" || ctx.sessionValue(ctxCfg.authFieldName) != ""
 }
 
 func (ctx *Ctx) IsGuest() bool {
-	return !ctx.session.Has(ctxCfg.authFieldName)
+	return !ctx.IsAuth()
 }
 
 func (ctx *Ctx) Logout() {
-	ctx.session.Empty()
+	revokeRememberToken(ctx)
+
+	ctx.authId = ""
+	ctx.guard = nil
+
+	if data := ctx.peekSession(); data != nil {
+		data.Empty()
+		ctx.regenerateSession()
+	}
+}
+
+func (ctx *Ctx) regenerateSession() {
+	if !ctx.sessionOpen {
+		return
+	}
+
+	if err := ctx.sessionManager.Regenerate(ctx.session); err != nil {
+		iLogError(err.Error())
+	}
 }
 
 func (ctx *Ctx) RequestValidatorInput() *input.Data {
@@ -238,11 +338,15 @@ func (ctx *Ctx) Form() *CtxForm {
 }
 
 func (ctx *Ctx) CsrfToken() string {
-	if !ctx.session.Has(ctxCfg.csrfFieldName) {
-		ctx.session.Set(ctxCfg.csrfFieldName, utils.UUID())
+	if ctx.stateless {
+		return ""
+	}
+
+	if !ctx.Session().Has(ctxCfg.csrfFieldName) {
+		ctx.Session().Set(ctxCfg.csrfFieldName, utils.UUID())
 	}
 
-	return ctx.session.Get(ctxCfg.csrfFieldName)
+	return ctx.Session().Get(ctxCfg.csrfFieldName)
 }
 
 func (ctx *Ctx) CsrfFieldName() string {
@@ -326,6 +430,23 @@ func (ctx *Ctx) parseForm() error {
 	return nil
 }
 
+func (ctx *Ctx) CacheTags(tags ...string) {
+	ctx.cacheTags = append(ctx.cacheTags, tags...)
+}
+
+func (ctx *Ctx) InvalidatePageCache(tags ...string) error {
+	return cachePageMiddlewareCfg.store.InvalidateTags(tags...)
+}
+
+func (ctx *Ctx) InvalidatePageCacheRoute(names ...string) error {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = pageCacheRouteTagPrefix + name
+	}
+
+	return cachePageMiddlewareCfg.store.InvalidateTags(tags...)
+}
+
 func (ctx *Ctx) ErrBag() *ErrorBag {
 	return ctx.flashStorage.Errors()
 }
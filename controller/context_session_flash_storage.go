@@ -1,11 +1,22 @@
 package controller
 
-import "github.com/censoredgit/light/session"
+import (
+	"github.com/censoredgit/light/session"
+	"net/url"
+)
+
+const (
+	flashInputKey      = "_input"
+	flashOldInputKey   = "_old_input"
+	flashErrorInputKey = "_error_input"
+)
 
 type ContextSessionFlashStorage struct {
 	sessionData *session.Data
+	open        func(create bool) *session.Data
 	errorBag    *ErrorBag
 	inputBag    *InputBag
+	isLoaded    bool
 }
 
 func NewContextSessionFlashStorage(sessionData *session.Data) ContextFlashStorage {
@@ -15,88 +26,107 @@ func NewContextSessionFlashStorage(sessionData *session.Data) ContextFlashStorag
 		inputBag:    newInputBag(),
 	}
 
-	c.init()
+	c.load()
 
 	return c
 }
 
-func (c *ContextSessionFlashStorage) init() {
-	if c.sessionData.Has("_error_input") {
-		val := c.sessionData.Get("_error_input")
-		if val != "" {
-			errorInput, err := base64ToErrorBag(val)
-			if err != nil {
-				iLogError(err.Error())
-			} else {
-				c.errorBag.err = errorInput.Errors()
+// newLazyContextSessionFlashStorage reads flashes only when the bags are used
+// and starts a session only when there is something to flash.
+func newLazyContextSessionFlashStorage(ctx *Ctx) ContextFlashStorage {
+	return &ContextSessionFlashStorage{
+		open: func(create bool) *session.Data {
+			if create {
+				return ctx.Session()
 			}
-			c.sessionData.Delete("_error_input")
-		}
+			return ctx.peekSession()
+		},
+		errorBag: newErrorBag(),
+		inputBag: newInputBag(),
 	}
+}
 
-	if c.sessionData.Has("_old_input") {
-		val := c.sessionData.Get("_old_input")
-		if val != "" {
-			values, err := base64ToValues(val)
-			if err != nil {
-				iLogError(err.Error())
-			} else {
-				c.inputBag.old = values
-			}
-			c.sessionData.Delete("_old_input")
-		}
+func (c *ContextSessionFlashStorage) load() {
+	if c.isLoaded {
+		return
 	}
+	c.isLoaded = true
 
-	if c.sessionData.Has("_input") {
-		val := c.sessionData.Get("_input")
-		if val != "" {
-			values, err := base64ToValues(val)
-			if err != nil {
-				iLogError(err.Error())
-			} else {
-				c.inputBag.data = values
-			}
-			c.sessionData.Delete("_input")
-		}
+	if c.sessionData == nil {
+		c.sessionData = c.open(false)
 	}
 
+	if c.sessionData != nil {
+		c.init()
+	}
 }
 
-func (c *ContextSessionFlashStorage) Flush() {
-	if len(c.inputBag.old) > 0 && c.inputBag.isModified {
-		oldInputStr, err := valuesToBase64(c.inputBag.old)
+func (c *ContextSessionFlashStorage) init() {
+	if c.sessionData.Has(flashErrorInputKey) {
+		errorInput, err := session.GetAs[map[string]string](c.sessionData, flashErrorInputKey)
+		if err != nil {
+			iLogError(err.Error())
+			c.sessionData.Delete(flashErrorInputKey)
+		} else if errorInput != nil {
+			c.errorBag.err = errorInput
+		}
+	}
 
+	if c.sessionData.Has(flashOldInputKey) {
+		values, err := session.GetAs[url.Values](c.sessionData, flashOldInputKey)
 		if err != nil {
 			iLogError(err.Error())
+			c.sessionData.Delete(flashOldInputKey)
 		} else {
-			c.sessionData.Set("_old_input", oldInputStr)
+			c.inputBag.old = values
 		}
 	}
 
-	if len(c.inputBag.data) > 0 && c.inputBag.isModified {
-		inputStr, err := valuesToBase64(c.inputBag.data)
-
+	if c.sessionData.Has(flashInputKey) {
+		values, err := session.GetAs[url.Values](c.sessionData, flashInputKey)
 		if err != nil {
 			iLogError(err.Error())
+			c.sessionData.Delete(flashInputKey)
 		} else {
-			c.sessionData.Set("_input", inputStr)
+			c.inputBag.data = values
+		}
+	}
+}
+
+func (c *ContextSessionFlashStorage) Flush() {
+	if !c.inputBag.isModified && !c.errorBag.isModified {
+		return
+	}
+
+	if c.sessionData == nil {
+		c.sessionData = c.open(true)
+	}
+
+	if len(c.inputBag.old) > 0 && c.inputBag.isModified {
+		if err := session.FlashAs(c.sessionData, flashOldInputKey, c.inputBag.old); err != nil {
+			iLogError(err.Error())
+		}
+	}
+
+	if len(c.inputBag.data) > 0 && c.inputBag.isModified {
+		if err := session.FlashAs(c.sessionData, flashInputKey, c.inputBag.data); err != nil {
+			iLogError(err.Error())
 		}
 	}
 
 	if len(c.errorBag.err) > 0 && c.errorBag.isModified {
-		errorStr, err := errorBagToBase64(c.errorBag)
-		if err != nil {
+		if err := session.FlashAs(c.sessionData, flashErrorInputKey, c.errorBag.err); err != nil {
 			iLogError(err.Error())
-		} else {
-			c.sessionData.Set("_error_input", errorStr)
 		}
 	}
 }
 
 func (c *ContextSessionFlashStorage) Errors() *ErrorBag {
+	c.load()
 	return c.errorBag
 }
 
 func (c *ContextSessionFlashStorage) Inputs() *InputBag {
+	c.load()
 	return c.inputBag
 }
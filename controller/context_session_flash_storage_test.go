@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"github.com/censoredgit/light/session"
+	"net/url"
+	"testing"
+)
+
+func TestContextSessionFlashStorageLegacyValues(t *testing.T) {
+	setupTestConfig()
+
+	legacy := func(value any) string {
+		b := bytes.Buffer{}
+		if err := gob.NewEncoder(&b).Encode(value); err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(b.Bytes())
+	}
+
+	data := session.NewData("", 0)
+	data.Set(flashErrorInputKey, legacy(map[string]string{"name": "required"}))
+	data.Set(flashOldInputKey, legacy(url.Values{"name": {"old"}}))
+	data.Set(flashInputKey, legacy(url.Values{"name": {"new"}}))
+
+	storage := NewContextSessionFlashStorage(data)
+
+	if len(storage.Errors().Errors()) != 0 || len(storage.Inputs().All()) != 0 {
+		t.Error("undecodable flash values should be ignored")
+	}
+
+	for _, key := range []string{flashErrorInputKey, flashOldInputKey, flashInputKey} {
+		if data.Has(key) {
+			t.Errorf("undecodable flash value %s should be removed", key)
+		}
+	}
+}
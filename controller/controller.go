@@ -78,6 +78,26 @@ func MustSetup(cfg *Config) *Controller {
 		config.StaticPath = defaultStaticPath
 	}
 
+	if config.IdempotencyStore == nil {
+		config.IdempotencyStore = NewMemoryResponseStore()
+	}
+
+	if config.IdempotencyTTL == 0 {
+		config.IdempotencyTTL = defaultIdempotencyKeysTTL
+	}
+
+	if config.PageCacheStore == nil {
+		config.PageCacheStore = NewMemoryResponseStore()
+	}
+
+	if strings.TrimSpace(config.RememberCookieName) == "" {
+		config.RememberCookieName = defaultRememberCookieName
+	}
+
+	if config.RememberTTL == 0 {
+		config.RememberTTL = defaultRememberTTL
+	}
+
 	return newController()
 }
 
@@ -130,7 +150,14 @@ func (c *Controller) Serve() error {
 		backRedirectKey,
 	)
 	setupCsrfMiddleware(config.CsrfFieldName)
-	setupLockMiddleware(locker.New(&locker.Config{}))
+
+	locks := config.Locker
+	if locks == nil {
+		locks = locker.New(&locker.Config{})
+	}
+	setupLockMiddleware(locks)
+	setupIdempotentMiddleware(config.IdempotencyStore, config.IdempotencyTTL, locks)
+	setupCachePageMiddleware(config.PageCacheStore)
 
 	return http.ListenAndServe(net.JoinHostPort(config.Host, config.Port), nil)
 }
@@ -199,6 +226,9 @@ func (c *Controller) resolveGroups(mountInfo map[string]*MountInfo, group *Group
 		}
 
 		info.action.WithMiddleware(group.composeMiddleware()...)
+		if info.action.sessionManager == nil {
+			info.action.sessionManager = group.composeSessionManager()
+		}
 
 		mountInfo[uri] = info
 	}
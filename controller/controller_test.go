@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"github.com/censoredgit/light/session"
 	"testing"
 )
 
@@ -22,3 +23,41 @@ func TestControllerDeepUriPathSuccess(t *testing.T) {
 		t.Error("path should be /a/b/c")
 	}
 }
+
+func TestControllerGroupSessionManager(t *testing.T) {
+	admin := &session.Manager{}
+	own := &session.Manager{}
+
+	overridden := NewAction(func(ctx *Ctx) (Response, error) {
+		return nil, nil
+	}).WithSessionManager(own)
+	inherited := NewAction(func(ctx *Ctx) (Response, error) {
+		return nil, nil
+	})
+	public := NewAction(func(ctx *Ctx) (Response, error) {
+		return nil, nil
+	})
+
+	ctr := newController()
+	ctr.Group().Prefix("/admin-sm").SessionManager(admin).Mount(func(m *Mount) {
+		m.Group().Prefix("/users").Mount(func(m *Mount) {
+			m.Get("/list", inherited)
+			m.Get("/own", overridden)
+		})
+	})
+	ctr.Get("/public-sm", public)
+
+	if err := ctr.composeRouters(); err != nil {
+		t.Fatal(err)
+	}
+
+	if inherited.sessionManager != admin {
+		t.Error("subgroup should inherit group session manager")
+	}
+	if overridden.sessionManager != own {
+		t.Error("action session manager should take precedence")
+	}
+	if public.sessionManager != nil {
+		t.Error("routes outside group should use default session manager")
+	}
+}
@@ -21,6 +21,10 @@ func Csrf() *CsrfMiddleware {
 }
 
 func (a *CsrfMiddleware) Next(ctx *Ctx) (Response, error) {
+	if ctx.stateless {
+		return ctx.Next()
+	}
+
 	csrf := ctx.CsrfToken()
 
 	inputCsrf := ""
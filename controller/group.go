@@ -1,13 +1,17 @@
 package controller
 
-import "strings"
+import (
+	"github.com/censoredgit/light/session"
+	"strings"
+)
 
 type Group struct {
-	parent      *Group
-	m           *Mount
-	prefix      string
-	parameters  bool
-	middlewares []Middleware
+	parent         *Group
+	m              *Mount
+	prefix         string
+	parameters     bool
+	middlewares    []Middleware
+	sessionManager *session.Manager
 }
 
 func (g *Group) hasParameters() bool {
@@ -36,6 +40,13 @@ func (g *Group) composeMiddleware() []Middleware {
 	return middlewares
 }
 
+func (g *Group) composeSessionManager() *session.Manager {
+	if g.sessionManager == nil && g.parent != nil && g.parent != g {
+		return g.parent.composeSessionManager()
+	}
+	return g.sessionManager
+}
+
 func (g *Group) Prefix(prefix string) *Group {
 	g.prefix = prefix
 	g.parameters = strings.Contains(prefix, "{")
@@ -49,6 +60,14 @@ func (g *Group) Middlewares(middlewares ...Middleware) *Group {
 	return g
 }
 
+// SessionManager overrides the session manager for the group routes and its
+// subgroups. The manager should use its own cookie name.
+func (g *Group) SessionManager(manager *session.Manager) *Group {
+	g.sessionManager = manager
+
+	return g
+}
+
 func (g *Group) Mount(f func(m *Mount)) {
 	f(g.m)
 }
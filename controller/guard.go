@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const defaultBasicRealm = "Restricted"
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+type Guard interface {
+	Authenticate(ctx *Ctx) (string, error)
+	Challenge(ctx *Ctx) (Response, error)
+}
+
+type SessionGuard struct{}
+
+func BySession() *SessionGuard {
+	return &SessionGuard{}
+}
+
+func (g *SessionGuard) Authenticate(ctx *Ctx) (string, error) {
+	return ctx.sessionValue(ctxCfg.authFieldName), nil
+}
+
+func (g *SessionGuard) Challenge(ctx *Ctx) (Response, error) {
+	if authMiddlewareCfg.loginRouteUri == "" {
+		return nil, errors.New("login uri required")
+	}
+
+	ref := ctx.Request().Referer()
+	if ref == "" && ctx.Request().RequestURI != authMiddlewareCfg.loginRouteUri {
+		ref = ctx.Request().RequestURI
+	}
+	if ref != "" {
+		ctx.Session().Set(authMiddlewareCfg.backAfterAuthQueryParam, ref)
+	}
+
+	return ctx.RedirectResponse(authMiddlewareCfg.loginRouteUri), nil
+}
+
+type BearerGuard struct {
+	provider TokenProvider
+}
+
+func ByBearer(provider TokenProvider) *BearerGuard {
+	return &BearerGuard{provider: provider}
+}
+
+func (g *BearerGuard) Authenticate(ctx *Ctx) (string, error) {
+	// the auth scheme is case-insensitive, RFC 9110 section 11.1
+	scheme, token, found := strings.Cut(ctx.Request().Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", nil
+	}
+
+	authId, err := g.provider.GetAuthIdByToken(ctx, token)
+	if errors.Is(err, ErrUnauthenticated) {
+		return "", nil
+	}
+
+	return authId, err
+}
+
+func (g *BearerGuard) Challenge(ctx *Ctx) (Response, error) {
+	ctx.httpResponse.Header().Set("WWW-Authenticate", "Bearer")
+
+	return ctx.CodeResponse(http.StatusUnauthorized), nil
+}
+
+type BasicGuard struct {
+	provider CredentialsProvider
+	realm    string
+}
+
+func ByBasic(provider CredentialsProvider) *BasicGuard {
+	return &BasicGuard{provider: provider, realm: defaultBasicRealm}
+}
+
+func (g *BasicGuard) Realm(realm string) *BasicGuard {
+	g.realm = realm
+
+	return g
+}
+
+func (g *BasicGuard) Authenticate(ctx *Ctx) (string, error) {
+	username, password, ok := ctx.Request().BasicAuth()
+	if !ok {
+		return "", nil
+	}
+
+	authId, err := g.provider.GetAuthIdByCredentials(ctx, username, password)
+	if errors.Is(err, ErrUnauthenticated) {
+		return "", nil
+	}
+
+	return authId, err
+}
+
+func (g *BasicGuard) Challenge(ctx *Ctx) (Response, error) {
+	ctx.httpResponse.Header().Set("WWW-Authenticate", `Basic realm="`+g.realm+`", charset="UTF-8"`)
+
+	return ctx.CodeResponse(http.StatusUnauthorized), nil
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testTokenProvider struct{}
+
+func (p testTokenProvider) GetAuthIdByToken(ctx context.Context, token string) (string, error) {
+	if token == "secret" {
+		return "api-user", nil
+	}
+	return "", ErrUnauthenticated
+}
+
+func (p testTokenProvider) GetAuthIdByCredentials(ctx context.Context, username, password string) (string, error) {
+	if username == "admin" && password == "pass" {
+		return "basic-user", nil
+	}
+	return "", ErrUnauthenticated
+}
+
+func TestGuards(t *testing.T) {
+	setupTestConfig()
+
+	a := newTestAction("/api", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.AuthIdentification(), http.StatusOK), nil
+	}, Auth(ByBearer(testTokenProvider{}), ByBasic(testTokenProvider{})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	if res := serveTestAction(a, req); res.Code != http.StatusOK || res.Body.String() != "api-user" {
+		t.Errorf("bearer auth failed: %d %q", res.Code, res.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "bearer secret")
+	if res := serveTestAction(a, req); res.Code != http.StatusOK || res.Body.String() != "api-user" {
+		t.Errorf("bearer scheme should be case-insensitive: %d %q", res.Code, res.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.SetBasicAuth("admin", "pass")
+	if res := serveTestAction(a, req); res.Code != http.StatusOK || res.Body.String() != "basic-user" {
+		t.Errorf("basic auth failed: %d %q", res.Code, res.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	res := serveTestAction(a, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", res.Code)
+	}
+	if res.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("expected bearer challenge, got %q", res.Header().Get("WWW-Authenticate"))
+	}
+}
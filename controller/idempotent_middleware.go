@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"errors"
+	"github.com/censoredgit/light/locker"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyKeyMaxLength   = 255
+	defaultIdempotencyKeysTTL = 24 * time.Hour
+)
+
+var idempotentMiddlewareCfg struct {
+	store  ResponseStore
+	ttl    time.Duration
+	locker locker.SimpleLocker
+}
+
+func setupIdempotentMiddleware(store ResponseStore, ttl time.Duration, locker locker.SimpleLocker) {
+	idempotentMiddlewareCfg.store = store
+	idempotentMiddlewareCfg.ttl = ttl
+	idempotentMiddlewareCfg.locker = locker
+}
+
+type IdempotentMiddleware struct{}
+
+func Idempotent() *IdempotentMiddleware {
+	return &IdempotentMiddleware{}
+}
+
+func (a *IdempotentMiddleware) Next(ctx *Ctx) (Response, error) {
+	if !ctx.IsPost() && !ctx.IsPut() && !ctx.IsPatch() {
+		return ctx.Next()
+	}
+
+	key := strings.TrimSpace(ctx.Request().Header.Get(idempotencyKeyHeader))
+	if key == "" {
+		return ctx.Next()
+	}
+
+	if len(key) > idempotencyKeyMaxLength {
+		return ctx.CodeResponse(http.StatusBadRequest), nil
+	}
+
+	storeKey, ok := a.storeKey(ctx, key)
+	if !ok {
+		return ctx.Next()
+	}
+
+	err := idempotentMiddlewareCfg.locker.SimpleLock(storeKey)
+	if err != nil {
+		if errors.Is(err, locker.ErrLockTimeOut) {
+			return ctx.CodeResponse(http.StatusConflict), nil
+		}
+		return nil, err
+	}
+	defer idempotentMiddlewareCfg.locker.ReleaseSimpleLock(storeKey)
+
+	stored, found, err := idempotentMiddlewareCfg.store.Get(storeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		return newReplayResponse(stored, map[string]string{idempotentReplayedHeader: "true"}, ctx.flashStorage), nil
+	}
+
+	response, err := ctx.Next()
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	// the response is recorded while the lock is held, so a duplicate
+	// request waiting for it finds the stored result
+	stored = recordResponse(ctx, response)
+
+	if stored.Code < http.StatusInternalServerError {
+		if err := idempotentMiddlewareCfg.store.Set(storeKey, stored, idempotentMiddlewareCfg.ttl); err != nil {
+			ctx.Log().Error(err.Error())
+		}
+	}
+
+	return newReplayResponse(stored, nil, ctx.flashStorage), nil
+}
+
+func (a *IdempotentMiddleware) Priority() uint {
+	return 150
+}
+
+// storeKey scopes the key to the client and the endpoint. A guest without a
+// stored session, on a stateless route for example, can not be told apart
+// from other guests, so its requests are not deduplicated.
+func (a *IdempotentMiddleware) storeKey(ctx *Ctx, key string) (string, bool) {
+	var scope string
+	if ctx.IsAuth() {
+		scope = "auth:" + ctx.AuthIdentification()
+	} else if id := ctx.Session().Id(); id != "" {
+		scope = "session:" + id
+	} else {
+		return "", false
+	}
+
+	endpoint := ctx.Request().Method + " " + ctx.Request().URL.Path
+
+	storeKey := strings.Builder{}
+	storeKey.WriteString("IdempotentMiddleware_")
+	for _, part := range []string{scope, endpoint} {
+		storeKey.WriteString(strconv.Itoa(len(part)))
+		storeKey.WriteString(":")
+		storeKey.WriteString(part)
+	}
+	storeKey.WriteString(key)
+
+	return storeKey.String(), true
+}
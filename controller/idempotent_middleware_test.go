@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/censoredgit/light/locker"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIdempotentReplay(t *testing.T) {
+	setupTestConfig()
+
+	var calls atomic.Int32
+	a := newTestAction("/orders", func(ctx *Ctx) (Response, error) {
+		n := calls.Add(1)
+		return ctx.TextResponse(fmt.Sprintf("order %d", n), http.StatusCreated), nil
+	}, Idempotent())
+
+	cookies := startTestSession()
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(idempotencyKeyHeader, key)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		return serveTestAction(a, req)
+	}
+
+	res := send("key-1")
+	if res.Code != http.StatusCreated || res.Body.String() != "order 1" {
+		t.Fatalf("unexpected response %d %q", res.Code, res.Body.String())
+	}
+
+	replay := send("key-1")
+	if replay.Code != http.StatusCreated || replay.Body.String() != "order 1" {
+		t.Fatalf("unexpected replay %d %q", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Error("replay header expected")
+	}
+	if replay.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Error("stored headers should be replayed")
+	}
+
+	if other := send("key-2"); other.Body.String() != "order 2" {
+		t.Errorf("other key should not be replayed, got %q", other.Body.String())
+	}
+}
+
+func TestIdempotentConcurrentDuplicates(t *testing.T) {
+	setupTestConfig()
+
+	var calls atomic.Int32
+	a := newTestAction("/orders", func(ctx *Ctx) (Response, error) {
+		calls.Add(1)
+		return ctx.TextResponse("created", http.StatusCreated), nil
+	}, Idempotent())
+
+	cookies := startTestSession()
+
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.Header.Set(idempotencyKeyHeader, "same")
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+			if res := serveTestAction(a, req); res.Code != http.StatusCreated {
+				t.Errorf("unexpected code %d", res.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler should run once, ran %d times", calls.Load())
+	}
+}
+
+type discardResponseMiddleware struct{}
+
+func (m *discardResponseMiddleware) Next(ctx *Ctx) (Response, error) {
+	if _, err := ctx.Next(); err != nil {
+		return nil, err
+	}
+	return ctx.CodeResponse(http.StatusAccepted), nil
+}
+
+func (m *discardResponseMiddleware) Priority() uint {
+	return 100
+}
+
+func TestIdempotentDiscardedResponse(t *testing.T) {
+	setupTestConfig()
+
+	var calls atomic.Int32
+	a := newTestAction("/orders", func(ctx *Ctx) (Response, error) {
+		calls.Add(1)
+		return ctx.TextResponse("created", http.StatusCreated), nil
+	}, Idempotent(), &discardResponseMiddleware{})
+
+	cookies := startTestSession()
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(idempotencyKeyHeader, "same")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		serveTestAction(a, req)
+
+		if stats := idempotentMiddlewareCfg.locker.(*locker.Locker).Stats(); stats.WriteLocked != 0 {
+			t.Fatalf("lock should be released when the response is discarded, got %+v", stats)
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("handler result should be stored, ran %d times", calls.Load())
+	}
+}
+
+func TestIdempotentStoreKey(t *testing.T) {
+	setupTestConfig()
+
+	m := Idempotent()
+	storeKey := func(authId, method, path, key string) string {
+		storeKey, ok := m.storeKey(&Ctx{authId: authId, request: httptest.NewRequest(method, path, nil)}, key)
+		if !ok {
+			t.Fatal("authenticated request should be scoped")
+		}
+		return storeKey
+	}
+
+	if storeKey("a_b", http.MethodPost, "/orders", "c") == storeKey("a", http.MethodPost, "/orders", "b_c") {
+		t.Error("store keys of different scopes should differ")
+	}
+	if storeKey("a", http.MethodPost, "/orders", "1") == storeKey("a", http.MethodPost, "/refunds", "1") {
+		t.Error("store keys of different paths should differ")
+	}
+	if storeKey("a", http.MethodPost, "/orders", "1") == storeKey("a", http.MethodPut, "/orders", "1") {
+		t.Error("store keys of different methods should differ")
+	}
+}
+
+func TestIdempotentWithoutSession(t *testing.T) {
+	setupTestConfig()
+
+	var calls atomic.Int32
+	a := newTestAction("/hook", func(ctx *Ctx) (Response, error) {
+		n := calls.Add(1)
+		return ctx.TextResponse(fmt.Sprintf("hook %d", n), http.StatusCreated), nil
+	}, Idempotent(), Stateless())
+
+	for i := range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+		req.Header.Set(idempotencyKeyHeader, "1")
+		if res := serveTestAction(a, req); res.Body.String() != fmt.Sprintf("hook %d", i+1) {
+			t.Errorf("guests without session should not share responses, got %q", res.Body.String())
+		}
+	}
+}
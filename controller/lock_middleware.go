@@ -6,10 +6,10 @@ import (
 )
 
 var lockMiddlewareCfg struct {
-	locker *locker.Locker
+	locker locker.SimpleLocker
 }
 
-func setupLockMiddleware(locker *locker.Locker) {
+func setupLockMiddleware(locker locker.SimpleLocker) {
 	lockMiddlewareCfg.locker = locker
 }
 
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/censoredgit/light/session"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRememberCookieName = "_remember"
+	defaultRememberTTL        = 30 * 24 * time.Hour
+	rememberSelectorLength    = 12
+	rememberValidatorLength   = 32
+	rememberRotationGrace     = 30 * time.Second
+)
+
+var ErrRememberNotConfigured = errors.New("remember token provider not configured")
+
+type RememberToken struct {
+	Selector      string
+	ValidatorHash string
+	AuthId        string
+	Expire        time.Time
+	// Rotated is set once the token is replaced by a new one. The rotated
+	// token is kept for a short grace, so parallel requests sent with it
+	// do not drop the new cookie.
+	Rotated time.Time
+}
+
+type LoginOption func(opts *loginOptions)
+
+type loginOptions struct {
+	remember bool
+}
+
+func Remember() LoginOption {
+	return func(opts *loginOptions) {
+		opts.remember = true
+	}
+}
+
+func RevokeRememberTokens(ctx context.Context, authId string) error {
+	if config.RememberTokenProvider == nil {
+		return ErrRememberNotConfigured
+	}
+
+	return config.RememberTokenProvider.DeleteRememberTokens(ctx, authId)
+}
+
+func handleRemember(ctx *Ctx) {
+	if config.RememberTokenProvider == nil || ctx.stateless || ctx.IsAuth() {
+		return
+	}
+
+	selector, validator, ok := rememberCookieValue(ctx.request)
+	if !ok {
+		return
+	}
+
+	token, err := config.RememberTokenProvider.FindRememberToken(ctx, selector)
+	if err != nil {
+		iLogError(err.Error())
+		return
+	}
+
+	if token == nil || token.Expire.Before(time.Now()) {
+		forgetRememberCookie(ctx)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashRememberValidator(validator)), []byte(token.ValidatorHash)) != 1 {
+		iLogError("remember token validator mismatch, revoking all tokens of " + token.AuthId)
+		if err = config.RememberTokenProvider.DeleteRememberTokens(ctx, token.AuthId); err != nil {
+			iLogError(err.Error())
+		}
+		forgetRememberCookie(ctx)
+		return
+	}
+
+	if !token.Rotated.IsZero() {
+		// a parallel request rotated the token, the client gets the new cookie from it
+		return
+	}
+
+	token.Rotated = time.Now()
+	if grace := token.Rotated.Add(rememberRotationGrace); grace.Before(token.Expire) {
+		token.Expire = grace
+	}
+	if err = config.RememberTokenProvider.StoreRememberToken(ctx, token); err != nil {
+		iLogError(err.Error())
+		return
+	}
+
+	if err = issueRememberToken(ctx, token.AuthId); err != nil {
+		iLogError(err.Error())
+		return
+	}
+
+	ctx.Session().Set(ctxCfg.authFieldName, token.AuthId)
+	ctx.Session().SetOwner(token.AuthId, session.NewMetadata(ctx.request))
+	ctx.regenerateSession()
+}
+
+func issueRememberToken(ctx *Ctx, authId string) error {
+	if config.RememberTokenProvider == nil {
+		return ErrRememberNotConfigured
+	}
+
+	selector, err := randomRememberString(rememberSelectorLength)
+	if err != nil {
+		return err
+	}
+
+	validator, err := randomRememberString(rememberValidatorLength)
+	if err != nil {
+		return err
+	}
+
+	token := &RememberToken{
+		Selector:      selector,
+		ValidatorHash: hashRememberValidator(validator),
+		AuthId:        authId,
+		Expire:        time.Now().Add(config.RememberTTL),
+	}
+
+	if err = config.RememberTokenProvider.StoreRememberToken(ctx, token); err != nil {
+		return err
+	}
+
+	http.SetCookie(ctx.httpResponse, &http.Cookie{
+		Name:     config.RememberCookieName,
+		Value:    selector + ":" + validator,
+		Path:     "/",
+		Expires:  token.Expire,
+		HttpOnly: true,
+		Secure:   config.Protocol == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func revokeRememberToken(ctx *Ctx) {
+	if config.RememberTokenProvider == nil {
+		return
+	}
+
+	selector, _, ok := rememberCookieValue(ctx.request)
+	if !ok {
+		return
+	}
+
+	if err := config.RememberTokenProvider.DeleteRememberToken(ctx, selector); err != nil {
+		iLogError(err.Error())
+	}
+
+	forgetRememberCookie(ctx)
+}
+
+func forgetRememberCookie(ctx *Ctx) {
+	http.SetCookie(ctx.httpResponse, &http.Cookie{
+		Name:     config.RememberCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+func rememberCookieValue(req *http.Request) (string, string, bool) {
+	if req == nil {
+		return "", "", false
+	}
+
+	c, err := req.Cookie(config.RememberCookieName)
+	if err != nil {
+		return "", "", false
+	}
+
+	selector, validator, found := strings.Cut(c.Value, ":")
+	if !found || selector == "" || validator == "" {
+		return "", "", false
+	}
+
+	return selector, validator, true
+}
+
+func randomRememberString(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRememberValidator(validator string) string {
+	sum := sha256.Sum256([]byte(validator))
+	return hex.EncodeToString(sum[:])
+}
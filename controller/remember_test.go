@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testUser string
+
+func (u testUser) AuthId() string {
+	return string(u)
+}
+
+func (u testUser) IsActive() bool {
+	return true
+}
+
+type testRememberProvider struct {
+	lock   sync.Mutex
+	tokens map[string]*RememberToken
+}
+
+func (p *testRememberProvider) StoreRememberToken(ctx context.Context, token *RememberToken) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	stored := *token
+	p.tokens[token.Selector] = &stored
+	return nil
+}
+
+func (p *testRememberProvider) FindRememberToken(ctx context.Context, selector string) (*RememberToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	token, ok := p.tokens[selector]
+	if !ok {
+		return nil, nil
+	}
+	found := *token
+	return &found, nil
+}
+
+func (p *testRememberProvider) DeleteRememberToken(ctx context.Context, selector string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.tokens, selector)
+	return nil
+}
+
+func (p *testRememberProvider) DeleteRememberTokens(ctx context.Context, authId string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for selector, token := range p.tokens {
+		if token.AuthId == authId {
+			delete(p.tokens, selector)
+		}
+	}
+	return nil
+}
+
+func rememberCookie(res *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range res.Result().Cookies() {
+		if c.Name == config.RememberCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestRememberLogin(t *testing.T) {
+	setupTestConfig()
+	provider := &testRememberProvider{tokens: make(map[string]*RememberToken)}
+	config.RememberTokenProvider = provider
+	config.RememberCookieName = defaultRememberCookieName
+	config.RememberTTL = defaultRememberTTL
+
+	login := newTestAction("/login", func(ctx *Ctx) (Response, error) {
+		ctx.Login(testUser("john"), Remember())
+		return ctx.TextResponse("ok", http.StatusOK), nil
+	})
+	me := newTestAction("/me", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.AuthIdentification(), http.StatusOK), nil
+	})
+
+	issued := rememberCookie(serveTestAction(login, httptest.NewRequest(http.MethodPost, "/login", nil)))
+	if issued == nil {
+		t.Fatal("remember cookie should be issued")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(issued)
+	res := serveTestAction(me, req)
+	if res.Body.String() != "john" {
+		t.Fatalf("user should be logged in by remember cookie, got %q", res.Body.String())
+	}
+
+	rotated := rememberCookie(res)
+	if rotated == nil || rotated.Value == issued.Value {
+		t.Fatal("remember cookie should be rotated")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(issued)
+	if res = serveTestAction(me, req); res.Body.String() != "" {
+		t.Fatalf("used remember cookie should be rejected, got %q", res.Body.String())
+	}
+	if rememberCookie(res) != nil {
+		t.Fatal("rotated remember cookie should not be touched within the grace")
+	}
+
+	selector, _, _ := strings.Cut(issued.Value, ":")
+	provider.tokens[selector].Expire = time.Now().Add(-time.Second)
+
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(issued)
+	if forgotten := rememberCookie(serveTestAction(me, req)); forgotten == nil || forgotten.MaxAge >= 0 {
+		t.Fatal("rotated remember cookie should be forgotten after the grace")
+	}
+
+	if err := RevokeRememberTokens(context.Background(), "john"); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.AddCookie(rotated)
+	if res = serveTestAction(me, req); res.Body.String() != "" {
+		t.Fatalf("revoked remember cookie should be rejected, got %q", res.Body.String())
+	}
+}
+
+func TestRememberParallel(t *testing.T) {
+	setupTestConfig()
+	provider := &testRememberProvider{tokens: make(map[string]*RememberToken)}
+	config.RememberTokenProvider = provider
+	config.RememberCookieName = defaultRememberCookieName
+	config.RememberTTL = defaultRememberTTL
+
+	login := newTestAction("/login", func(ctx *Ctx) (Response, error) {
+		ctx.Login(testUser("john"), Remember())
+		return ctx.TextResponse("ok", http.StatusOK), nil
+	})
+	me := newTestAction("/me", func(ctx *Ctx) (Response, error) {
+		return ctx.TextResponse(ctx.AuthIdentification(), http.StatusOK), nil
+	})
+
+	issued := rememberCookie(serveTestAction(login, httptest.NewRequest(http.MethodPost, "/login", nil)))
+	if issued == nil {
+		t.Fatal("remember cookie should be issued")
+	}
+
+	responses := make([]*httptest.ResponseRecorder, 2)
+	wg := sync.WaitGroup{}
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.AddCookie(issued)
+			responses[i] = serveTestAction(me, req)
+		}()
+	}
+	wg.Wait()
+
+	loggedIn := 0
+	for _, res := range responses {
+		if res.Body.String() == "john" {
+			loggedIn++
+		}
+		if c := rememberCookie(res); c != nil && c.MaxAge < 0 {
+			t.Fatal("parallel request should not forget the rotated remember cookie")
+		}
+	}
+	if loggedIn == 0 {
+		t.Fatal("user should be logged in by remember cookie")
+	}
+}
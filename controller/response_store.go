@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+const memoryResponseStorePurgeInterval = time.Minute
+
+type ResponseStore interface {
+	Get(key string) (*StoredResponse, bool, error)
+	Set(key string, response *StoredResponse, ttl time.Duration) error
+	Delete(key string) error
+}
+
+type PageCacheStore interface {
+	ResponseStore
+	Tag(key string, tags ...string) error
+	InvalidateTags(tags ...string) error
+}
+
+type memoryResponseStoreItem struct {
+	response *StoredResponse
+	expire   time.Time
+}
+
+type MemoryResponseStore struct {
+	lock      sync.Mutex
+	items     map[string]*memoryResponseStoreItem
+	tags      map[string]map[string]struct{}
+	lastPurge time.Time
+}
+
+func NewMemoryResponseStore() *MemoryResponseStore {
+	return &MemoryResponseStore{
+		items:     make(map[string]*memoryResponseStoreItem),
+		tags:      make(map[string]map[string]struct{}),
+		lastPurge: time.Now(),
+	}
+}
+
+func (s *MemoryResponseStore) Get(key string) (*StoredResponse, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	item, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if item.expire.Before(time.Now()) {
+		delete(s.items, key)
+		return nil, false, nil
+	}
+
+	return item.response, true, nil
+}
+
+func (s *MemoryResponseStore) Set(key string, response *StoredResponse, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	currentTime := time.Now()
+
+	s.items[key] = &memoryResponseStoreItem{
+		response: response,
+		expire:   currentTime.Add(ttl),
+	}
+
+	if s.lastPurge.Add(memoryResponseStorePurgeInterval).Before(currentTime) {
+		s.purge(currentTime)
+	}
+
+	return nil
+}
+
+func (s *MemoryResponseStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.items, key)
+
+	return nil
+}
+
+func (s *MemoryResponseStore) Tag(key string, tags ...string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, tag := range tags {
+		if _, ok := s.tags[tag]; !ok {
+			s.tags[tag] = make(map[string]struct{})
+		}
+		s.tags[tag][key] = struct{}{}
+	}
+
+	return nil
+}
+
+func (s *MemoryResponseStore) InvalidateTags(tags ...string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			delete(s.items, key)
+		}
+		delete(s.tags, tag)
+	}
+
+	return nil
+}
+
+func (s *MemoryResponseStore) purge(currentTime time.Time) {
+	for key, item := range s.items {
+		if item.expire.Before(currentTime) {
+			delete(s.items, key)
+		}
+	}
+
+	for tag, keys := range s.tags {
+		for key := range keys {
+			if _, ok := s.items[key]; !ok {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+
+	s.lastPurge = currentTime
+}
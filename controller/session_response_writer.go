@@ -0,0 +1,35 @@
+package controller
+
+import "net/http"
+
+type sessionResponseWriter struct {
+	http.ResponseWriter
+	beforeWrite func()
+	committed   bool
+}
+
+func newSessionResponseWriter(w http.ResponseWriter, beforeWrite func()) *sessionResponseWriter {
+	return &sessionResponseWriter{ResponseWriter: w, beforeWrite: beforeWrite}
+}
+
+func (w *sessionResponseWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionResponseWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *sessionResponseWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	w.beforeWrite()
+}
@@ -0,0 +1,17 @@
+package controller
+
+// StatelessMiddleware marks a route or group as stateless: no session is
+// started, flashes are dropped and csrf is not checked.
+type StatelessMiddleware struct{}
+
+func Stateless() *StatelessMiddleware {
+	return &StatelessMiddleware{}
+}
+
+func (a *StatelessMiddleware) Next(ctx *Ctx) (Response, error) {
+	return ctx.Next()
+}
+
+func (a *StatelessMiddleware) Priority() uint {
+	return 0
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+)
+
+type StoredResponse struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+func (s *StoredResponse) writeTo(w http.ResponseWriter) {
+	for k, v := range s.Header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+
+	w.WriteHeader(s.Code)
+	_, _ = w.Write(s.Body)
+}
+
+type responseRecorder struct {
+	header      http.Header
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header: make(http.Header),
+		code:   http.StatusOK,
+	}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.code = code
+	r.wroteHeader = true
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) Result() *StoredResponse {
+	return &StoredResponse{
+		Code:   r.code,
+		Header: r.header.Clone(),
+		Body:   bytes.Clone(r.body.Bytes()),
+	}
+}
+
+type RecordedResponse struct {
+	Response
+	onRecorded func(stored *StoredResponse)
+}
+
+func newRecordedResponse(response Response, onRecorded func(stored *StoredResponse)) *RecordedResponse {
+	return &RecordedResponse{Response: response, onRecorded: onRecorded}
+}
+
+func (r *RecordedResponse) Process(ctx *Ctx) {
+	stored := recordResponse(ctx, r.Response)
+	r.onRecorded(stored)
+	stored.writeTo(ctx.httpResponse)
+}
+
+func recordResponse(ctx *Ctx, response Response) *StoredResponse {
+	recorder := newResponseRecorder()
+	origin := ctx.httpResponse
+
+	ctx.httpResponse = recorder
+	response.Process(ctx)
+	ctx.httpResponse = origin
+
+	return recorder.Result()
+}
+
+type ReplayResponse struct {
+	stored *StoredResponse
+	header map[string]string
+	CommonResponse
+}
+
+func newReplayResponse(stored *StoredResponse, header map[string]string, flashStorage ContextFlashStorage) *ReplayResponse {
+	return &ReplayResponse{
+		stored: stored,
+		header: header,
+		CommonResponse: CommonResponse{
+			code:         stored.Code,
+			flashStorage: flashStorage,
+		},
+	}
+}
+
+func (c *ReplayResponse) Process(ctx *Ctx) {
+	for k, v := range c.header {
+		ctx.httpResponse.Header().Set(k, v)
+	}
+
+	c.stored.writeTo(ctx.httpResponse)
+}
+
+func (c *ReplayResponse) With(it func(response ResponseExtendData)) Response {
+	it(c)
+	return c
+}
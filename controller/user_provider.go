@@ -6,3 +6,20 @@ type UserProvider interface {
 	GetAuthIdentification(ctx context.Context, authId string) (AuthIdentification, error)
 	GetRoleSupport(ctx context.Context, authId string) (RoleSupport, error)
 }
+
+type TokenProvider interface {
+	GetAuthIdByToken(ctx context.Context, token string) (string, error)
+}
+
+type CredentialsProvider interface {
+	GetAuthIdByCredentials(ctx context.Context, username, password string) (string, error)
+}
+
+// RememberTokenProvider stores remember tokens by selector. StoreRememberToken
+// replaces a stored token with the same selector.
+type RememberTokenProvider interface {
+	StoreRememberToken(ctx context.Context, token *RememberToken) error
+	FindRememberToken(ctx context.Context, selector string) (*RememberToken, error)
+	DeleteRememberToken(ctx context.Context, selector string) error
+	DeleteRememberTokens(ctx context.Context, authId string) error
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/censoredgit/light/locker"
 	"github.com/censoredgit/light/session"
 	"github.com/censoredgit/light/session/driver/memory"
-	"github.com/censoredgit/light/session/hasher"
 	"github.com/censoredgit/light/validator"
 	"github.com/censoredgit/light/validator/rules"
 	"io"
@@ -47,7 +46,6 @@ func main() {
 	locks := locker.New(&locker.Config{GCTimeout: time.Hour})
 
 	sessionManager := session.MustSetup(&session.Config{
-		Salt:       "salt",
 		TTL:        time.Hour,
 		CookieName: "_test",
 		Driver: memory.Setup(
@@ -56,8 +54,10 @@ func main() {
 			time.Hour,
 			memory.DefaultGarbageListInitCap,
 		),
-		Logger: logger,
-		Hasher: &hasher.Md5Hasher{},
+		Logger:         logger,
+		IdGenerator:    session.NewSecureIdGenerator(session.DefaultIdBytes),
+		CookieHttpOnly: true,
+		Sliding:        true,
 	})
 
 	c := controller.MustSetup(&controller.Config{
@@ -151,14 +151,11 @@ func main() {
 	})
 
 	c.Get("/profile", controller.NewAction(func(ctx *controller.Ctx) (controller.Response, error) {
-		counter := 0
-		if ctx.Session().Has("counter") {
-			counter, _ = strconv.Atoi(ctx.Session().Get("counter"))
+		counter, err := ctx.Session().Increment("counter", 1)
+		if err != nil {
+			return nil, err
 		}
 
-		counter++
-		ctx.Session().Set("counter", strconv.Itoa(counter))
-
 		return ctx.TemplateInlineResponse(
 			`
 					<html>
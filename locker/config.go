@@ -4,4 +4,6 @@ import "time"
 
 type Config struct {
 	GCTimeout time.Duration
+	// AcquireTimeout limits SimpleLock waiting, DefaultAcquireTimeout when zero.
+	AcquireTimeout time.Duration
 }
@@ -0,0 +1,187 @@
+package locker
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type waiter struct {
+	ready chan struct{}
+	write bool
+	lease *lease
+}
+
+// entry is a read-write lock which hands the lock over to its waiters in
+// FIFO order. A reader arriving while somebody waits queues up as well, so
+// writers are not starved.
+//
+// refs counts holders and waiters. It is incremented only under
+// Locker.lock, so the GC may remove an entry once refs drops to zero.
+//
+// limit caps the readers, a semaphore is an entry with a limit.
+type entry struct {
+	mu      sync.Mutex
+	readers int
+	limit   int
+	writer  bool
+	waiters list.List
+	refs    atomic.Int32
+	lease   *lease
+}
+
+func (e *entry) RUnlock() {
+	if e.unlock(false) {
+		e.refs.Add(-1)
+	}
+}
+
+func (e *entry) Unlock() {
+	if e.unlock(true) {
+		e.refs.Add(-1)
+	}
+}
+
+func (e *entry) lock(ctx context.Context, write bool) error {
+	return e.wait(ctx, &waiter{write: write})
+}
+
+// lockLease takes the write lock and installs le in the same critical
+// section, so the lock is never held without its lease.
+func (e *entry) lockLease(ctx context.Context, le *lease) error {
+	return e.wait(ctx, &waiter{write: true, lease: le})
+}
+
+func (e *entry) wait(ctx context.Context, w *waiter) error {
+	e.mu.Lock()
+	if e.waiters.Len() == 0 && e.available(w.write) {
+		e.acquire(w)
+		e.mu.Unlock()
+		return nil
+	}
+
+	w.ready = make(chan struct{})
+	el := e.waiters.PushBack(w)
+	e.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	e.mu.Lock()
+	select {
+	case <-w.ready:
+		// granted while giving up, hand it over to the next waiter
+		switch {
+		case w.lease == nil:
+			e.release(w.write)
+		case e.lease == w.lease:
+			w.lease.timer.Stop()
+			e.endLease()
+		default:
+			// the lease expired and handed the lock over already
+			e.mu.Unlock()
+			return errLeaseExpired
+		}
+	default:
+		isFront := e.waiters.Front() == el
+		e.waiters.Remove(el)
+		if isFront {
+			e.grant()
+		}
+	}
+	e.mu.Unlock()
+
+	return ErrLockTimeOut
+}
+
+func (e *entry) tryLock(write bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.waiters.Len() > 0 || !e.available(write) {
+		return false
+	}
+
+	e.acquire(&waiter{write: write})
+
+	return true
+}
+
+// unlock releases a lock taken without a lease, a leased lock is released
+// only by its owner.
+func (e *entry) unlock(write bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if write && e.lease != nil {
+		return false
+	}
+
+	return e.release(write)
+}
+
+func (e *entry) available(write bool) bool {
+	if write {
+		return !e.writer && e.readers == 0
+	}
+
+	return !e.writer && (e.limit == 0 || e.readers < e.limit)
+}
+
+func (e *entry) acquire(w *waiter) {
+	if !w.write {
+		e.readers++
+		return
+	}
+
+	e.writer = true
+	if w.lease != nil {
+		e.startLease(w.lease)
+	}
+}
+
+func (e *entry) release(write bool) bool {
+	if write {
+		if !e.writer {
+			return false
+		}
+		e.writer = false
+	} else {
+		if e.readers == 0 {
+			return false
+		}
+		e.readers--
+	}
+
+	e.grant()
+
+	return true
+}
+
+func (e *entry) grant() {
+	for el := e.waiters.Front(); el != nil; el = e.waiters.Front() {
+		w := el.Value.(*waiter)
+		if !e.available(w.write) {
+			return
+		}
+
+		e.acquire(w)
+		e.waiters.Remove(el)
+		close(w.ready)
+
+		if w.write {
+			return
+		}
+	}
+}
+
+func (e *entry) waiting() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.waiters.Len()
+}
@@ -0,0 +1,12 @@
+package flock
+
+import "time"
+
+// Config of the flock locker. Lock files are kept after release and are
+// removed by Locker.Cleanup.
+type Config struct {
+	AcquireTimeout time.Duration
+	RetryTimeout   time.Duration
+	// GCTimeout is passed to the in-process locker.
+	GCTimeout time.Duration
+}
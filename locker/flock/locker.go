@@ -0,0 +1,206 @@
+package flock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/censoredgit/light/locker"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultAcquireTimeout = locker.DefaultAcquireTimeout
+const defaultRetryTimeout = 10 * time.Millisecond
+const defaultGCTimeout = 10 * time.Minute
+const lockFileExt = ".lock"
+const dirPerm = 0o700
+const filePerm = 0o600
+
+// Locker takes advisory flock locks on per key files, so processes sharing
+// the directory exclude each other. Goroutines of one process queue up in an
+// in-process locker first and only the head of the queue polls the file.
+// A lock file is created per key and kept after release, call Cleanup
+// periodically to remove the idle ones.
+type Locker struct {
+	dir   string
+	cfg   Config
+	local *locker.Locker
+	files sync.Map
+}
+
+var _ locker.SimpleLocker = (*Locker)(nil)
+
+func New(dir string, cfg *Config) (*Locker, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("flock locker error: %w", err)
+	}
+
+	l := &Locker{dir: dir, cfg: *cfg}
+
+	if l.cfg.AcquireTimeout <= 0 {
+		l.cfg.AcquireTimeout = defaultAcquireTimeout
+	}
+	if l.cfg.RetryTimeout <= 0 {
+		l.cfg.RetryTimeout = defaultRetryTimeout
+	}
+	if l.cfg.GCTimeout <= 0 {
+		l.cfg.GCTimeout = defaultGCTimeout
+	}
+
+	l.local = locker.New(&locker.Config{GCTimeout: l.cfg.GCTimeout})
+
+	return l, nil
+}
+
+func (l *Locker) SimpleLock(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.cfg.AcquireTimeout)
+	defer cancel()
+
+	return l.SimpleLockWithContext(ctx, id)
+}
+
+func (l *Locker) SimpleLockWithContext(ctx context.Context, id string) error {
+	if err := l.local.SimpleLockWithContext(ctx, id); err != nil {
+		return err
+	}
+
+	path := l.path(id)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, filePerm)
+		if err != nil {
+			l.local.ReleaseSimpleLock(id)
+			return fmt.Errorf("flock lock error: %w", err)
+		}
+
+		locked, err := l.lockFile(ctx, f)
+		if err != nil {
+			_ = f.Close()
+			l.local.ReleaseSimpleLock(id)
+			return err
+		}
+
+		// Cleanup may have removed the file before it was locked
+		if locked && isLinked(f, path) {
+			currentTime := time.Now()
+			_ = os.Chtimes(path, currentTime, currentTime)
+			l.files.Store(id, f)
+			return nil
+		}
+
+		_ = unlockFile(f)
+		_ = f.Close()
+	}
+}
+
+func (l *Locker) lockFile(ctx context.Context, f *os.File) (bool, error) {
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			return false, fmt.Errorf("flock lock error: %w", err)
+		}
+		if locked {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, locker.ErrLockTimeOut
+		case <-time.After(l.cfg.RetryTimeout):
+		}
+	}
+}
+
+func (l *Locker) ReleaseSimpleLock(id string) {
+	if f, ok := l.files.LoadAndDelete(id); ok {
+		_ = unlockFile(f.(*os.File))
+		_ = f.(*os.File).Close()
+	}
+
+	l.local.ReleaseSimpleLock(id)
+}
+
+// Cleanup removes lock files which were not locked for olderThan. A file is
+// removed only while it is locked, and a locker which locked a removed file
+// takes the lock again on a new one.
+func (l *Locker) Cleanup(olderThan time.Duration) error {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return fmt.Errorf("flock cleanup error: %w", err)
+	}
+
+	threshold := time.Now().Add(-olderThan)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != lockFileExt {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(threshold) {
+			continue
+		}
+
+		if err = l.remove(filepath.Join(l.dir, entry.Name()), threshold); err != nil {
+			return fmt.Errorf("flock cleanup error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *Locker) remove(path string, threshold time.Time) error {
+	f, err := os.OpenFile(path, os.O_RDWR, filePerm)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// closing the file releases the lock
+	locked, err := tryLockFile(f)
+	if err != nil || !locked {
+		return err
+	}
+
+	// the file may have been locked and replaced since it was listed
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !isLinked(f, path) || info.ModTime().After(threshold) {
+		return nil
+	}
+
+	if err = os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func isLinked(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	linked, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(opened, linked)
+}
+
+func (l *Locker) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+
+	return filepath.Join(l.dir, hex.EncodeToString(sum[:])+lockFileExt)
+}
@@ -0,0 +1,240 @@
+package flock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/censoredgit/light/locker"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const helperDirEnv = "FLOCK_TEST_HELPER_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(helperDirEnv); dir != "" {
+		holdLock(dir)
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// holdLock runs in the helper process: it takes the lock, reports it and
+// holds it until stdin is closed.
+func holdLock(dir string) {
+	l, err := New(dir, &Config{})
+	if err != nil {
+		os.Exit(1)
+	}
+	if err = l.SimpleLock("test"); err != nil {
+		os.Exit(1)
+	}
+
+	_, _ = os.Stdout.WriteString("locked\n")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+
+	l.ReleaseSimpleLock("test")
+}
+
+func setupLocker(t *testing.T, dir string) *Locker {
+	l, err := New(dir, &Config{AcquireTimeout: 200 * time.Millisecond, RetryTimeout: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestSimpleLock(t *testing.T) {
+	dir := t.TempDir()
+	first, second := setupLocker(t, dir), setupLocker(t, dir)
+
+	if err := first.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.SimpleLock("test"); !errors.Is(err, locker.ErrLockTimeOut) {
+		t.Fatalf("lock held by other locker should time out, got %v", err)
+	}
+	if err := second.SimpleLock("other"); err != nil {
+		t.Fatalf("other key should not be locked: %v", err)
+	}
+	second.ReleaseSimpleLock("other")
+
+	first.ReleaseSimpleLock("test")
+
+	if err := second.SimpleLock("test"); err != nil {
+		t.Fatalf("released lock should be taken: %v", err)
+	}
+	second.ReleaseSimpleLock("test")
+	second.ReleaseSimpleLock("test")
+}
+
+func TestCrossProcess(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), helperDirEnv+"="+dir)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "locked\n" {
+		_ = stdin.Close()
+		t.Fatalf("helper process should take the lock, got %q %v", line, err)
+	}
+
+	l := setupLocker(t, dir)
+	if err = l.SimpleLock("test"); !errors.Is(err, locker.ErrLockTimeOut) {
+		_ = stdin.Close()
+		t.Fatalf("lock held by other process should time out, got %v", err)
+	}
+
+	_ = stdin.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = l.SimpleLockWithContext(ctx, "test"); err != nil {
+		t.Fatalf("lock released by other process should be taken: %v", err)
+	}
+	l.ReleaseSimpleLock("test")
+}
+
+func TestMutualExclusion(t *testing.T) {
+	dir := t.TempDir()
+	lockers := []*Locker{setupLocker(t, dir), setupLocker(t, dir)}
+
+	var active, counter atomic.Int32
+	wg := sync.WaitGroup{}
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := lockers[i%2]
+			for range 20 {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				err := l.SimpleLockWithContext(ctx, "test")
+				cancel()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if active.Add(1) != 1 {
+					t.Error("mutual exclusion lost")
+				}
+				time.Sleep(time.Microsecond)
+				active.Add(-1)
+				counter.Add(1)
+				l.ReleaseSimpleLock("test")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter.Load() != 8*20 {
+		t.Errorf("counter should be %d, got %d", 8*20, counter.Load())
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	dir := t.TempDir()
+	l := setupLocker(t, dir)
+
+	for _, id := range []string{"held", "idle"} {
+		if err := l.SimpleLock(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.ReleaseSimpleLock("idle")
+
+	past := time.Now().Add(-time.Hour)
+	for _, id := range []string{"held", "idle"} {
+		if err := os.Chtimes(l.path(id), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.Cleanup(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(l.path("idle")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("idle lock file should be removed")
+	}
+	if _, err := os.Stat(l.path("held")); err != nil {
+		t.Error("held lock file should be kept")
+	}
+
+	if err := setupLocker(t, dir).SimpleLock("held"); !errors.Is(err, locker.ErrLockTimeOut) {
+		t.Fatalf("held lock should stay locked, got %v", err)
+	}
+	l.ReleaseSimpleLock("held")
+
+	if err := l.SimpleLock("idle"); err != nil {
+		t.Fatal(err)
+	}
+	l.ReleaseSimpleLock("idle")
+}
+
+func TestCleanupWhileLocking(t *testing.T) {
+	dir := t.TempDir()
+	lockers := []*Locker{setupLocker(t, dir), setupLocker(t, dir)}
+
+	var holders, overlaps atomic.Int32
+	done := make(chan struct{})
+	cleaned := make(chan struct{})
+	go func() {
+		defer close(cleaned)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if err := lockers[0].Cleanup(0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for _, l := range lockers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if err := l.SimpleLockWithContext(context.Background(), "test"); err != nil {
+					t.Error(err)
+					return
+				}
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(100 * time.Microsecond)
+				holders.Add(-1)
+				l.ReleaseSimpleLock("test")
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-cleaned
+
+	if overlaps.Load() != 0 {
+		t.Errorf("lock should be exclusive while files are removed, overlapped %d times", overlaps.Load())
+	}
+}
@@ -0,0 +1,228 @@
+package locker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var ErrNotOwner = errors.New("lease is not owned")
+var ErrInvalidTTL = errors.New("lease ttl must be positive")
+
+// errLeaseExpired is returned by lockLease when the lease was granted and
+// expired while the caller gave up, its reference is released already.
+var errLeaseExpired = fmt.Errorf("%w: lease expired", ErrLockTimeOut)
+
+type lease struct {
+	token    string
+	holder   string
+	ttl      time.Duration
+	acquired time.Time
+	expires  time.Time
+	timer    *time.Timer
+}
+
+// Lease is an exclusive lock which expires unless it is extended. Only the
+// owner token releases or extends it, an expired or released lease returns
+// ErrNotOwner.
+type Lease struct {
+	key   string
+	token string
+	entry *entry
+}
+
+type LeaseInfo struct {
+	Key      string
+	Holder   string
+	Acquired time.Time
+	Expires  time.Time
+	Waiters  int
+}
+
+func (i LeaseInfo) Age() time.Duration {
+	return time.Since(i.Acquired)
+}
+
+// Acquire waits for the key until ctx is done and leases it for ttl. The
+// lease shares the key with WriteLock and SimpleLock.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error) {
+	if ttl <= 0 {
+		return Lease{}, ErrInvalidTTL
+	}
+
+	token, err := newLeaseToken()
+	if err != nil {
+		return Lease{}, fmt.Errorf("lease acquire error: %w", err)
+	}
+
+	holder := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		holder = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	e := l.acquire(key)
+	if err = e.lockLease(ctx, &lease{token: token, holder: holder, ttl: ttl}); err != nil {
+		if errors.Is(err, errLeaseExpired) {
+			return Lease{}, ErrLockTimeOut
+		}
+		e.refs.Add(-1)
+		return Lease{}, err
+	}
+
+	return Lease{key: key, token: token, entry: e}, nil
+}
+
+// ReleaseLease releases the key if token owns its lease.
+func (l *Locker) ReleaseLease(key, token string) error {
+	e := l.get(key)
+	if e == nil {
+		return ErrNotOwner
+	}
+
+	return e.releaseLease(token)
+}
+
+// Leases returns the held leases ordered by key.
+func (l *Locker) Leases() []LeaseInfo {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	infos := make([]LeaseInfo, 0)
+	for key, e := range l.storage {
+		e.mu.Lock()
+		if e.lease != nil {
+			infos = append(infos, LeaseInfo{
+				Key:      key,
+				Holder:   e.lease.holder,
+				Acquired: e.lease.acquired,
+				Expires:  e.lease.expires,
+				Waiters:  e.waiters.Len(),
+			})
+		}
+		e.mu.Unlock()
+	}
+
+	slices.SortFunc(infos, func(a, b LeaseInfo) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return infos
+}
+
+// DumpLeases writes the held leases as a table for debugging.
+func (l *Locker) DumpLeases(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KEY\tHOLDER\tAGE\tEXPIRES IN\tWAITERS")
+
+	for _, info := range l.Leases() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n",
+			info.Key,
+			info.Holder,
+			info.Age().Truncate(time.Millisecond),
+			time.Until(info.Expires).Truncate(time.Millisecond),
+			info.Waiters,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func (le Lease) Key() string {
+	return le.key
+}
+
+func (le Lease) Token() string {
+	return le.token
+}
+
+func (le Lease) Extend(ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	if le.entry == nil {
+		return ErrNotOwner
+	}
+
+	e := le.entry
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lease == nil || e.lease.token != le.token {
+		return ErrNotOwner
+	}
+
+	e.lease.expires = time.Now().Add(ttl)
+	e.lease.timer.Reset(ttl)
+
+	return nil
+}
+
+func (le Lease) Release() error {
+	if le.entry == nil {
+		return ErrNotOwner
+	}
+
+	return le.entry.releaseLease(le.token)
+}
+
+func (e *entry) releaseLease(token string) error {
+	e.mu.Lock()
+	if e.lease == nil || e.lease.token != token {
+		e.mu.Unlock()
+		return ErrNotOwner
+	}
+
+	e.lease.timer.Stop()
+	e.endLease()
+	e.mu.Unlock()
+
+	e.refs.Add(-1)
+
+	return nil
+}
+
+func (e *entry) expireLease(token string) {
+	e.mu.Lock()
+	// the lease may have been extended after the timer fired
+	if e.lease == nil || e.lease.token != token || time.Now().Before(e.lease.expires) {
+		e.mu.Unlock()
+		return
+	}
+
+	e.endLease()
+	e.mu.Unlock()
+
+	e.refs.Add(-1)
+}
+
+func (e *entry) startLease(le *lease) {
+	currentTime := time.Now()
+
+	le.acquired = currentTime
+	le.expires = currentTime.Add(le.ttl)
+	le.timer = time.AfterFunc(le.ttl, func() { e.expireLease(le.token) })
+	e.lease = le
+}
+
+func (e *entry) endLease() {
+	e.lease = nil
+	e.release(true)
+}
+
+func newLeaseToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
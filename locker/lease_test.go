@@ -0,0 +1,223 @@
+package locker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaseOwnerRelease(t *testing.T) {
+	locker := New(&Config{})
+
+	lease, err := locker.Acquire(context.Background(), "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker.ReleaseSimpleLock("test")
+	if err = locker.ReleaseLease("test", "foreign"); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("foreign token should not release lease, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = locker.Acquire(ctx, "test", time.Minute); !errors.Is(err, ErrLockTimeOut) {
+		t.Fatalf("leased key should stay locked, got %v", err)
+	}
+
+	if err = locker.ReleaseLease("test", lease.Token()); err != nil {
+		t.Fatal(err)
+	}
+	if err = lease.Release(); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("released lease should not be released twice, got %v", err)
+	}
+	if err = lease.Extend(time.Minute); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("released lease should not be extended, got %v", err)
+	}
+
+	if err = locker.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	locker.ReleaseSimpleLock("test")
+
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("released lease should leave no entries, got %+v", stats)
+	}
+}
+
+func TestLeaseExpire(t *testing.T) {
+	locker := New(&Config{})
+
+	lease, err := locker.Acquire(context.Background(), "test", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	next, err := locker.Acquire(context.Background(), "test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("expired lease should wake up waiter, waited %v", waited)
+	}
+
+	if err = lease.Release(); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("expired lease should not release the next owner, got %v", err)
+	}
+	if err = next.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaseAcquireRace(t *testing.T) {
+	locker := New(&Config{})
+
+	for range 200 {
+		if err := locker.SimpleLock("test"); err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan struct{})
+		released := make(chan struct{})
+		go func() {
+			defer close(released)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					locker.ReleaseSimpleLock("test")
+				}
+			}
+		}()
+
+		lease, err := locker.Acquire(context.Background(), "test", time.Minute)
+		close(done)
+		<-released
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if unlocker, ok := locker.TryLock("test"); ok {
+			unlocker.Unlock()
+			t.Fatal("simple unlock should not release a granted lease")
+		}
+		if err = lease.Release(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLeaseExpiredWhileGivingUp(t *testing.T) {
+	locker := New(&Config{})
+
+	if err := locker.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	given := make(chan error, 1)
+	go func() {
+		_, err := locker.Acquire(ctx, "test", time.Hour)
+		given <- err
+	}()
+	waitForWaiters(t, locker, "test", 1)
+
+	next := make(chan Lease, 1)
+	go func() {
+		lease, err := locker.Acquire(context.Background(), "test", time.Hour)
+		if err != nil {
+			t.Error(err)
+		}
+		next <- lease
+	}()
+	waitForWaiters(t, locker, "test", 2)
+
+	// the first waiter gives up, is granted the lease and the lease expires
+	// before it takes the entry lock back
+	e := locker.get("test")
+	e.mu.Lock()
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	e.release(true)
+	e.refs.Add(-1)
+	e.lease.timer.Stop()
+	e.endLease()
+	e.refs.Add(-1)
+	e.mu.Unlock()
+
+	if err := <-given; !errors.Is(err, ErrLockTimeOut) {
+		t.Fatalf("given up waiter should time out, got %v", err)
+	}
+
+	lease := <-next
+	if stats := locker.Stats(); stats.WriteLocked != 1 || stats.Leases != 1 {
+		t.Fatalf("next lease should keep the key locked, got %+v", stats)
+	}
+	if refs := e.refs.Load(); refs != 1 {
+		t.Errorf("only the next lease should hold a reference, got %d", refs)
+	}
+
+	if err := lease.Release(); err != nil {
+		t.Fatal(err)
+	}
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("released lease should leave no entries, got %+v", stats)
+	}
+}
+
+func TestLeaseExtend(t *testing.T) {
+	locker := New(&Config{})
+
+	lease, err := locker.Acquire(context.Background(), "test", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = lease.Extend(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if infos := locker.Leases(); len(infos) != 1 || infos[0].Key != "test" {
+		t.Fatalf("extended lease should be held, got %+v", infos)
+	}
+	if err = lease.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaseDump(t *testing.T) {
+	locker := New(&Config{})
+
+	lease, err := locker.Acquire(context.Background(), "report", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	go func() {
+		_ = locker.SimpleLock("report")
+		locker.ReleaseSimpleLock("report")
+	}()
+	waitForWaiters(t, locker, "report", 1)
+
+	infos := locker.Leases()
+	if len(infos) != 1 || infos[0].Waiters != 1 || !strings.HasPrefix(infos[0].Holder, "lease_test.go:") {
+		t.Fatalf("unexpected leases %+v", infos)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = locker.DumpLeases(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "report") || !strings.Contains(buf.String(), "lease_test.go:") {
+		t.Errorf("dump should list the lease, got\n%s", buf.String())
+	}
+}
@@ -4,34 +4,31 @@ import (
 	"context"
 	"errors"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
-const acquireAttemptsTimeout = time.Second * 3
-const retryLockTimeout = time.Millisecond * 250
+const DefaultAcquireTimeout = time.Second * 3
 
 var ErrLockTimeOut = errors.New("lock timeout")
 
-type entry struct {
-	m sync.RWMutex
-	c atomic.Int32
-}
-
 type Locker struct {
 	lock    sync.RWMutex
 	storage map[string]*entry
 	cfg     *Config
 }
 
-func (e *entry) RUnlock() {
-	e.m.RUnlock()
-	e.c.Add(1)
-}
-
-func (e *entry) Unlock() {
-	e.m.Unlock()
-	e.c.Add(-1)
+type Stats struct {
+	// Entries is the number of keys in the storage, idle ones included.
+	Entries int
+	// WriteLocked is the number of keys held by a writer.
+	WriteLocked int
+	// ReadLocks is the number of read locks and semaphore permits held over
+	// all keys.
+	ReadLocks int
+	// Waiters is the number of goroutines waiting for a lock.
+	Waiters int
+	// Leases is the number of held leases, they are counted in WriteLocked too.
+	Leases int
 }
 
 func (l *Locker) len() int {
@@ -48,6 +45,14 @@ type ReadUnlocker interface {
 	RUnlock()
 }
 
+// SimpleLocker is the keyed exclusive lock implemented by the in-memory,
+// flock and redis lockers.
+type SimpleLocker interface {
+	SimpleLock(id string) error
+	SimpleLockWithContext(ctx context.Context, id string) error
+	ReleaseSimpleLock(id string)
+}
+
 func New(cfg *Config) *Locker {
 	l := &Locker{
 		lock:    sync.RWMutex{},
@@ -61,76 +66,129 @@ func New(cfg *Config) *Locker {
 }
 
 func (l *Locker) WriteLock(id string) WriteUnlocker {
-	e := l.getOrCreate(id)
-	e.m.Lock()
-	e.c.Add(1)
+	e := l.acquire(id)
+	_ = e.lock(context.Background(), true)
 
 	return e
 }
 
 func (l *Locker) ReadLock(id string) ReadUnlocker {
-	e := l.getOrCreate(id)
-	e.m.RLock()
-	e.c.Add(1)
+	e := l.acquire(id)
+	_ = e.lock(context.Background(), false)
 
 	return e
 }
 
+// SimpleLock waits for the lock at most Config.AcquireTimeout.
 func (l *Locker) SimpleLock(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), acquireAttemptsTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), l.acquireTimeout())
 	defer cancel()
 
 	return l.SimpleLockWithContext(ctx, id)
 }
 
+// SimpleLockWithContext waits for the lock until ctx is done. Waiters are
+// woken up in FIFO order as soon as the lock is released.
 func (l *Locker) SimpleLockWithContext(ctx context.Context, id string) error {
-	e := l.getOrCreate(id)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ErrLockTimeOut
-		default:
-			if e.m.TryLock() {
-				return nil
-			}
-			time.Sleep(retryLockTimeout)
-		}
+	e := l.acquire(id)
+	if err := e.lock(ctx, true); err != nil {
+		e.refs.Add(-1)
+		return err
 	}
+
+	return nil
 }
 
+// ReleaseSimpleLock releases the lock taken by SimpleLock. Releasing a lock
+// which is not held or is leased does nothing.
 func (l *Locker) ReleaseSimpleLock(id string) {
-	e := l.getOrCreate(id)
-	if e.m.TryLock() {
-		e.c.Add(1)
+	if e := l.get(id); e != nil {
+		e.Unlock()
+	}
+}
+
+// TryLock takes the write lock only if it is free and nobody waits for it.
+func (l *Locker) TryLock(id string) (WriteUnlocker, bool) {
+	e := l.acquire(id)
+	if !e.tryLock(true) {
+		e.refs.Add(-1)
+		return nil, false
+	}
+
+	return e, true
+}
+
+// TryRLock takes a read lock only if no writer holds or waits for it.
+func (l *Locker) TryRLock(id string) (ReadUnlocker, bool) {
+	e := l.acquire(id)
+	if !e.tryLock(false) {
+		e.refs.Add(-1)
+		return nil, false
+	}
+
+	return e, true
+}
+
+func (l *Locker) Stats() Stats {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	stats := Stats{Entries: len(l.storage)}
+	for _, e := range l.storage {
+		e.mu.Lock()
+		if e.writer {
+			stats.WriteLocked++
+		}
+		stats.ReadLocks += e.readers
+		stats.Waiters += e.waiters.Len()
+		if e.lease != nil {
+			stats.Leases++
+		}
+		e.mu.Unlock()
 	}
-	e.Unlock()
+
+	return stats
 }
 
-func (l *Locker) getOrCreate(id string) *entry {
-	var e *entry
-	var ok bool
+func (l *Locker) acquireTimeout() time.Duration {
+	if l.cfg.AcquireTimeout > 0 {
+		return l.cfg.AcquireTimeout
+	}
+
+	return DefaultAcquireTimeout
+}
 
+// acquire returns the entry of the key with a reference taken, the caller
+// must drop it when the lock is released or can not be taken.
+func (l *Locker) acquire(id string) *entry {
 	l.lock.RLock()
-	if e, ok = l.storage[id]; ok {
+	if e, ok := l.storage[id]; ok {
+		e.refs.Add(1)
 		l.lock.RUnlock()
 		return e
 	}
 	l.lock.RUnlock()
+
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
-	if e, ok = l.storage[id]; !ok {
-		e = &entry{
-			m: sync.RWMutex{},
-			c: atomic.Int32{},
-		}
+	e, ok := l.storage[id]
+	if !ok {
+		e = &entry{}
 		l.storage[id] = e
 	}
+	e.refs.Add(1)
 
 	return e
 }
 
+func (l *Locker) get(id string) *entry {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.storage[id]
+}
+
 func (l *Locker) runGC() {
 	if l.cfg.GCTimeout <= 0 {
 		return
@@ -139,23 +197,32 @@ func (l *Locker) runGC() {
 	for {
 		time.Sleep(l.cfg.GCTimeout)
 
-		l.lock.RLock()
-		garbage := make([]string, 0, max(1, len(l.storage)/2))
-		for id, e := range l.storage {
-			if e.c.Load() == 0 {
-				garbage = append(garbage, id)
-			}
+		l.collectGarbage()
+	}
+}
+
+// collectGarbage removes idle entries. References are only taken under
+// l.lock, so an entry without references can not be picked up while the
+// write lock is held.
+func (l *Locker) collectGarbage() {
+	l.lock.RLock()
+	garbage := make([]string, 0)
+	for id, e := range l.storage {
+		if e.refs.Load() == 0 {
+			garbage = append(garbage, id)
 		}
-		l.lock.RUnlock()
+	}
+	l.lock.RUnlock()
 
-		if len(garbage) > 0 {
-			l.lock.Lock()
-			for id, e := range l.storage {
-				if e.c.Load() == 0 {
-					delete(l.storage, id)
-				}
-			}
-			l.lock.Unlock()
+	if len(garbage) == 0 {
+		return
+	}
+
+	l.lock.Lock()
+	for _, id := range garbage {
+		if e, ok := l.storage[id]; ok && e.refs.Load() == 0 {
+			delete(l.storage, id)
 		}
 	}
+	l.lock.Unlock()
 }
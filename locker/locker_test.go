@@ -3,7 +3,9 @@ package locker
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -152,3 +154,321 @@ func TestSimpleLockCtx(t *testing.T) {
 
 	locker.ReleaseSimpleLock("test")
 }
+
+func TestSimpleLockWakeUp(t *testing.T) {
+	locker := New(&Config{})
+	if err := locker.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan time.Time)
+	go func() {
+		if err := locker.SimpleLock("test"); err != nil {
+			t.Error(err)
+		}
+		acquired <- time.Now()
+		locker.ReleaseSimpleLock("test")
+	}()
+
+	waitForWaiters(t, locker, "test", 1)
+	released := time.Now()
+	locker.ReleaseSimpleLock("test")
+
+	if wait := (<-acquired).Sub(released); wait > 50*time.Millisecond {
+		t.Errorf("waiter should be woken up immediately, waited %v", wait)
+	}
+}
+
+func TestSimpleLockFIFO(t *testing.T) {
+	locker := New(&Config{})
+	if err := locker.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan int, 5)
+	wg := sync.WaitGroup{}
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := locker.SimpleLock("test"); err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			locker.ReleaseSimpleLock("test")
+		}()
+		waitForWaiters(t, locker, "test", i+1)
+	}
+
+	locker.ReleaseSimpleLock("test")
+	wg.Wait()
+	close(order)
+
+	expected := 0
+	for i := range order {
+		if i != expected {
+			t.Fatalf("waiter %d acquired lock before waiter %d", i, expected)
+		}
+		expected++
+	}
+}
+
+func TestSimpleLockAcquireTimeout(t *testing.T) {
+	locker := New(&Config{AcquireTimeout: 100 * time.Millisecond})
+	if err := locker.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	defer locker.ReleaseSimpleLock("test")
+
+	start := time.Now()
+	if err := locker.SimpleLock("test"); !errors.Is(err, ErrLockTimeOut) {
+		t.Fatalf("expected ErrLockTimeOut, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("configured timeout should be used, waited %v", elapsed)
+	}
+	if n := locker.get("test").waiting(); n != 0 {
+		t.Errorf("timed out waiter should leave the queue, %d left", n)
+	}
+}
+
+func TestReadLockQueuesBehindWriter(t *testing.T) {
+	locker := New(&Config{})
+	r := locker.ReadLock("test")
+
+	events := make(chan string, 2)
+	go func() {
+		w := locker.WriteLock("test")
+		events <- "write"
+		w.Unlock()
+	}()
+	waitForWaiters(t, locker, "test", 1)
+
+	go func() {
+		r := locker.ReadLock("test")
+		events <- "read"
+		r.RUnlock()
+	}()
+	waitForWaiters(t, locker, "test", 2)
+
+	r.RUnlock()
+
+	if first := <-events; first != "write" {
+		t.Errorf("waiting writer should not be overtaken by a reader, got %s first", first)
+	}
+	<-events
+}
+
+func waitForWaiters(t *testing.T, locker *Locker, id string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for locker.get(id) == nil || locker.get(id).waiting() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// pollingLock is the former SimpleLock implementation, kept for benchmarks.
+type pollingLock struct {
+	m sync.Mutex
+}
+
+func (p *pollingLock) lock(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrLockTimeOut
+		default:
+			if p.m.TryLock() {
+				return nil
+			}
+			time.Sleep(250 * time.Millisecond)
+		}
+	}
+}
+
+func BenchmarkLockHandoff(b *testing.B) {
+	b.Run("polling", func(b *testing.B) {
+		p := &pollingLock{}
+		benchmarkHandoff(b, func() { _ = p.lock(context.Background()) }, p.m.Unlock)
+	})
+	b.Run("queue", func(b *testing.B) {
+		locker := New(&Config{})
+		benchmarkHandoff(b, func() { _ = locker.SimpleLock("test") }, func() { locker.ReleaseSimpleLock("test") })
+	})
+}
+
+func benchmarkHandoff(b *testing.B, lock, unlock func()) {
+	for b.Loop() {
+		lock()
+		done := make(chan struct{})
+		go func() {
+			lock()
+			unlock()
+			close(done)
+		}()
+		time.Sleep(time.Millisecond)
+		unlock()
+		<-done
+	}
+}
+
+func BenchmarkLockContended(b *testing.B) {
+	b.Run("polling", func(b *testing.B) {
+		p := &pollingLock{}
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = p.lock(context.Background())
+				p.m.Unlock()
+			}
+		})
+	})
+	b.Run("queue", func(b *testing.B) {
+		locker := New(&Config{})
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = locker.SimpleLock("test")
+				locker.ReleaseSimpleLock("test")
+			}
+		})
+	})
+}
+
+func TestLockerStats(t *testing.T) {
+	locker := New(&Config{})
+
+	w := locker.WriteLock("a")
+	r1 := locker.ReadLock("b")
+	r2 := locker.ReadLock("b")
+	go func() {
+		_ = locker.SimpleLock("a")
+		locker.ReleaseSimpleLock("a")
+	}()
+	waitForWaiters(t, locker, "a", 1)
+
+	if stats := locker.Stats(); stats != (Stats{Entries: 2, WriteLocked: 1, ReadLocks: 2, Waiters: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	w.Unlock()
+	r1.RUnlock()
+	r2.RUnlock()
+	r2.RUnlock()
+	locker.ReleaseSimpleLock("missing")
+
+	deadline := time.Now().Add(time.Second)
+	for locker.Stats().WriteLocked > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("idle entries should be collected, got %+v", stats)
+	}
+}
+
+func TestLockerGarbageKeepsHeld(t *testing.T) {
+	locker := New(&Config{})
+
+	w := locker.WriteLock("held")
+	locker.collectGarbage()
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = locker.SimpleLock("held")
+		close(acquired)
+		locker.ReleaseSimpleLock("held")
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("held entry should survive garbage collection")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Unlock()
+	<-acquired
+}
+
+func TestLockerStress(t *testing.T) {
+	locker := New(&Config{AcquireTimeout: time.Second})
+
+	const keys = 4
+	writers := make([]atomic.Int32, keys)
+	readers := make([]atomic.Int32, keys)
+
+	stop := make(chan struct{})
+	gcDone := make(chan struct{})
+	go func() {
+		defer close(gcDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				locker.collectGarbage()
+			}
+		}
+	}()
+
+	check := func(k int, write bool) {
+		if write {
+			if writers[k].Add(1) != 1 || readers[k].Load() != 0 {
+				t.Errorf("mutual exclusion lost on key %d", k)
+			}
+			time.Sleep(time.Microsecond)
+			writers[k].Add(-1)
+			return
+		}
+
+		readers[k].Add(1)
+		if writers[k].Load() != 0 {
+			t.Errorf("reader overlaps writer on key %d", k)
+		}
+		time.Sleep(time.Microsecond)
+		readers[k].Add(-1)
+	}
+
+	wg := sync.WaitGroup{}
+	for worker := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				k := (worker + i) % keys
+				id := strconv.Itoa(k)
+
+				switch i % 3 {
+				case 0:
+					w := locker.WriteLock(id)
+					check(k, true)
+					w.Unlock()
+				case 1:
+					r := locker.ReadLock(id)
+					check(k, false)
+					r.RUnlock()
+				default:
+					if err := locker.SimpleLock(id); err != nil {
+						t.Error(err)
+						continue
+					}
+					check(k, true)
+					locker.ReleaseSimpleLock(id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(stop)
+	<-gcDone
+
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("all entries should be collected, got %+v", stats)
+	}
+}
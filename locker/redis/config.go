@@ -0,0 +1,12 @@
+package redis
+
+import "time"
+
+type Config struct {
+	Prefix         string
+	TTL            time.Duration
+	AcquireTimeout time.Duration
+	RetryTimeout   time.Duration
+	// GCTimeout is passed to the in-process locker.
+	GCTimeout time.Duration
+}
@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/censoredgit/light/locker"
+	"github.com/censoredgit/light/resp"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultPrefix = "lock:"
+const defaultTTL = 30 * time.Second
+const defaultAcquireTimeout = 3 * time.Second
+const defaultRetryTimeout = 50 * time.Millisecond
+const defaultGCTimeout = 10 * time.Minute
+
+// Locker takes locks with SET NX PX. Goroutines of one process queue up in an
+// in-process locker first, so a key has one holder and one token per Locker.
+// The lock is extended every third of TTL while it is held, TTL only frees
+// the lock of a process which stopped without releasing it.
+type Locker struct {
+	client *resp.Client
+	cfg    Config
+	local  *locker.Locker
+	holds  sync.Map
+}
+
+var _ locker.SimpleLocker = (*Locker)(nil)
+
+type hold struct {
+	token string
+	stop  context.CancelFunc
+	done  chan struct{}
+}
+
+func New(client *resp.Client, cfg *Config) *Locker {
+	l := &Locker{client: client, cfg: *cfg}
+
+	if l.cfg.Prefix == "" {
+		l.cfg.Prefix = defaultPrefix
+	}
+	if l.cfg.TTL <= 0 {
+		l.cfg.TTL = defaultTTL
+	}
+	if l.cfg.AcquireTimeout <= 0 {
+		l.cfg.AcquireTimeout = defaultAcquireTimeout
+	}
+	if l.cfg.RetryTimeout <= 0 {
+		l.cfg.RetryTimeout = defaultRetryTimeout
+	}
+	if l.cfg.GCTimeout <= 0 {
+		l.cfg.GCTimeout = defaultGCTimeout
+	}
+
+	l.local = locker.New(&locker.Config{GCTimeout: l.cfg.GCTimeout})
+
+	return l
+}
+
+func (l *Locker) SimpleLock(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.cfg.AcquireTimeout)
+	defer cancel()
+
+	return l.SimpleLockWithContext(ctx, id)
+}
+
+func (l *Locker) SimpleLockWithContext(ctx context.Context, id string) error {
+	if err := l.local.SimpleLockWithContext(ctx, id); err != nil {
+		return err
+	}
+
+	if err := l.lock(ctx, id); err != nil {
+		l.local.ReleaseSimpleLock(id)
+		return err
+	}
+
+	return nil
+}
+
+func (l *Locker) lock(ctx context.Context, id string) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	for {
+		ok, err := l.client.SetNXPX(ctx, l.cfg.Prefix+id, token, l.cfg.TTL)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrDeadlineExceeded) {
+				return locker.ErrLockTimeOut
+			}
+			return err
+		}
+
+		if ok {
+			renewCtx, stop := context.WithCancel(context.Background())
+			h := &hold{token: token, stop: stop, done: make(chan struct{})}
+			l.holds.Store(id, h)
+			go l.renew(renewCtx, id, h)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return locker.ErrLockTimeOut
+		case <-time.After(l.cfg.RetryTimeout):
+		}
+	}
+}
+
+func (l *Locker) renew(ctx context.Context, id string, h *hold) {
+	defer close(h.done)
+
+	interval := l.cfg.TTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithTimeout(ctx, interval)
+		ok, err := l.client.CompareAndPExpire(renewCtx, l.cfg.Prefix+id, h.token, l.cfg.TTL)
+		cancel()
+
+		// the lock expired and may be held by somebody else
+		if err == nil && !ok {
+			return
+		}
+	}
+}
+
+func (l *Locker) ReleaseSimpleLock(id string) {
+	value, ok := l.holds.LoadAndDelete(id)
+	if !ok {
+		return
+	}
+
+	h := value.(*hold)
+	h.stop()
+	<-h.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.cfg.AcquireTimeout)
+	defer cancel()
+
+	_, _ = l.client.CompareAndDelete(ctx, l.cfg.Prefix+id, h.token)
+
+	l.local.ReleaseSimpleLock(id)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
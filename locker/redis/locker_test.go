@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/censoredgit/light/locker"
+	"github.com/censoredgit/light/resp"
+	"github.com/censoredgit/light/resp/resptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupLockers(t *testing.T, n int) []*Locker {
+	server, err := resptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	lockers := make([]*Locker, n)
+	for i := range n {
+		lockers[i] = New(resp.NewClient(&resp.Config{Addr: server.Addr()}), &Config{
+			TTL:            time.Second,
+			AcquireTimeout: time.Second * 2,
+			RetryTimeout:   time.Millisecond * 5,
+		})
+	}
+
+	return lockers
+}
+
+func TestSimpleLock(t *testing.T) {
+	lockers := setupLockers(t, 2)
+
+	if err := lockers[0].SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err := lockers[1].SimpleLockWithContext(ctx, "test"); !errors.Is(err, locker.ErrLockTimeOut) {
+		t.Fatalf("expected ErrLockTimeOut, got %v", err)
+	}
+
+	lockers[1].ReleaseSimpleLock("test")
+
+	lockers[0].ReleaseSimpleLock("test")
+	if err := lockers[1].SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	lockers[1].ReleaseSimpleLock("test")
+}
+
+// stall stops extending the lock as if the holding process hung.
+func stall(l *Locker, id string) {
+	value, _ := l.holds.Load(id)
+	h := value.(*hold)
+	h.stop()
+	<-h.done
+}
+
+func TestSimpleLockExpire(t *testing.T) {
+	lockers := setupLockers(t, 3)
+
+	if err := lockers[0].SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	stall(lockers[0], "test")
+
+	if err := lockers[1].SimpleLock("test"); err != nil {
+		t.Fatal("lock should be acquired after ttl:", err)
+	}
+	lockers[1].ReleaseSimpleLock("test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err := lockers[2].SimpleLockWithContext(ctx, "test"); err != nil {
+		t.Fatal("released lock should be taken at once:", err)
+	}
+
+	lockers[0].ReleaseSimpleLock("test")
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err := lockers[1].SimpleLockWithContext(ctx, "test"); !errors.Is(err, locker.ErrLockTimeOut) {
+		t.Fatal("expired owner should not release foreign lock")
+	}
+	lockers[2].ReleaseSimpleLock("test")
+}
+
+func TestSimpleLockRenew(t *testing.T) {
+	lockers := setupLockers(t, 2)
+
+	if err := lockers[0].SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*1500)
+	defer cancel()
+	if err := lockers[1].SimpleLockWithContext(ctx, "test"); !errors.Is(err, locker.ErrLockTimeOut) {
+		t.Fatalf("held lock should be extended past ttl, got %v", err)
+	}
+
+	lockers[0].ReleaseSimpleLock("test")
+	if err := lockers[1].SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+	lockers[1].ReleaseSimpleLock("test")
+}
+
+func TestSimpleLockSameLocker(t *testing.T) {
+	l := setupLockers(t, 1)[0]
+
+	if err := l.SimpleLock("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan error, 1)
+	go func() {
+		locked <- l.SimpleLock("test")
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("second holder of the same locker should wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.ReleaseSimpleLock("test")
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	l.ReleaseSimpleLock("test")
+}
+
+func TestMutualExclusion(t *testing.T) {
+	lockers := setupLockers(t, 4)
+
+	counter := 0
+	wg := sync.WaitGroup{}
+	for _, l := range lockers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if err := l.SimpleLock("counter"); err != nil {
+					t.Error(err)
+					return
+				}
+				value := counter
+				time.Sleep(time.Millisecond)
+				counter = value + 1
+				l.ReleaseSimpleLock("counter")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter != 40 {
+		t.Errorf("counter should be 40, got %d", counter)
+	}
+}
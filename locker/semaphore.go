@@ -0,0 +1,71 @@
+package locker
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphores live in the lock storage under their own key space
+const semaphoreKeyPrefix = "\x00semaphore:"
+
+// Semaphore lets at most n holders in for its key. Waiters are admitted in
+// FIFO order.
+type Semaphore struct {
+	locker *Locker
+	key    string
+	n      int
+}
+
+// Semaphore returns the semaphore of the key. n below one is treated as one.
+// Semaphores of one key share their holders, the last given n applies.
+func (l *Locker) Semaphore(key string, n int) *Semaphore {
+	return &Semaphore{
+		locker: l,
+		key:    semaphoreKeyPrefix + key,
+		n:      max(n, 1),
+	}
+}
+
+// Acquire waits for a permit until ctx is done. The returned release may be
+// called more than once.
+func (s *Semaphore) Acquire(ctx context.Context) (func(), error) {
+	e := s.entry()
+	if err := e.lock(ctx, false); err != nil {
+		e.refs.Add(-1)
+		return nil, err
+	}
+
+	return releaseOnce(e), nil
+}
+
+// TryAcquire takes a permit only if one is free and nobody waits for it.
+func (s *Semaphore) TryAcquire() (func(), bool) {
+	e := s.entry()
+	if !e.tryLock(false) {
+		e.refs.Add(-1)
+		return nil, false
+	}
+
+	return releaseOnce(e), true
+}
+
+func (s *Semaphore) entry() *entry {
+	e := s.locker.acquire(s.key)
+
+	e.mu.Lock()
+	if e.limit != s.n {
+		e.limit = s.n
+		e.grant()
+	}
+	e.mu.Unlock()
+
+	return e
+}
+
+func releaseOnce(e *entry) func() {
+	once := sync.Once{}
+
+	return func() {
+		once.Do(e.RUnlock)
+	}
+}
@@ -0,0 +1,125 @@
+package locker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	locker := New(&Config{})
+	sem := locker.Semaphore("exports", 2)
+
+	first, err := sem.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, ok := sem.TryAcquire()
+	if !ok {
+		t.Fatal("second permit should be free")
+	}
+	if _, ok = sem.TryAcquire(); ok {
+		t.Fatal("third permit should not be free")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = sem.Acquire(ctx); !errors.Is(err, ErrLockTimeOut) {
+		t.Fatalf("full semaphore should time out, got %v", err)
+	}
+
+	if unlock, ok := locker.TryLock("exports"); !ok {
+		t.Error("semaphore should not share keys with locks")
+	} else {
+		unlock.Unlock()
+	}
+
+	first()
+	first()
+	third, ok := sem.TryAcquire()
+	if !ok {
+		t.Fatal("released permit should be free")
+	}
+	if _, ok = sem.TryAcquire(); ok {
+		t.Fatal("double release should free one permit only")
+	}
+
+	second()
+	third()
+
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("released semaphore should be collected, got %+v", stats)
+	}
+}
+
+func TestSemaphoreConcurrency(t *testing.T) {
+	locker := New(&Config{})
+
+	var active, peak atomic.Int32
+	wg := sync.WaitGroup{}
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := locker.Semaphore("tenant", 3).Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := active.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			active.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 3 {
+		t.Errorf("at most 3 holders expected, got %d", peak.Load())
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	locker := New(&Config{})
+
+	w, ok := locker.TryLock("job")
+	if !ok {
+		t.Fatal("free lock should be taken")
+	}
+	if _, ok = locker.TryLock("job"); ok {
+		t.Fatal("held lock should not be taken")
+	}
+	if _, ok = locker.TryRLock("job"); ok {
+		t.Fatal("write locked key should not be read locked")
+	}
+	w.Unlock()
+
+	r, ok := locker.TryRLock("job")
+	if !ok {
+		t.Fatal("free key should be read locked")
+	}
+	if r2, ok := locker.TryRLock("job"); !ok {
+		t.Fatal("read locks should be shared")
+	} else {
+		r2.RUnlock()
+	}
+	if _, ok = locker.TryLock("job"); ok {
+		t.Fatal("read locked key should not be write locked")
+	}
+	r.RUnlock()
+
+	locker.collectGarbage()
+	if stats := locker.Stats(); stats != (Stats{}) {
+		t.Errorf("failed attempts should not leak references, got %+v", stats)
+	}
+}
@@ -0,0 +1,231 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const defaultPoolSize = 10
+const defaultDialTimeout = 5 * time.Second
+
+var ErrUnexpectedReply = errors.New("unexpected reply")
+
+type conn struct {
+	c net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+type Client struct {
+	cfg  Config
+	pool chan *conn
+}
+
+func NewClient(cfg *Config) *Client {
+	c := &Client{cfg: *cfg}
+
+	if c.cfg.PoolSize <= 0 {
+		c.cfg.PoolSize = defaultPoolSize
+	}
+	if c.cfg.DialTimeout <= 0 {
+		c.cfg.DialTimeout = defaultDialTimeout
+	}
+
+	c.pool = make(chan *conn, c.cfg.PoolSize)
+
+	return c
+}
+
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := cn.do(ctx, args...)
+	if err != nil {
+		_ = cn.c.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+
+	if respErr, ok := value.(Error); ok {
+		return nil, respErr
+	}
+
+	return value, nil
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if value == nil {
+		return nil, false, nil
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %v", ErrUnexpectedReply, value)
+	}
+
+	return b, true, nil
+}
+
+func (c *Client) SetPX(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.Do(ctx, "SET", key, string(value), "PX", formatMillis(ttl))
+	return err
+}
+
+func (c *Client) SetNXPX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	reply, err := c.Do(ctx, "SET", key, value, "NX", "PX", formatMillis(ttl))
+	if err != nil {
+		return false, err
+	}
+
+	return reply != nil, nil
+}
+
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	reply, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return asInt(reply)
+}
+
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...string) (any, error) {
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVAL", script, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+
+	return c.Do(ctx, cmd...)
+}
+
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.pool:
+			_ = cn.c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.cfg.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{c: netConn, r: bufio.NewReader(netConn), w: bufio.NewWriter(netConn)}
+
+	if c.cfg.Password != "" {
+		if err = cn.expectOk(ctx, "AUTH", c.cfg.Password); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+
+	if c.cfg.DB != 0 {
+		if err = cn.expectOk(ctx, "SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.pool <- cn:
+	default:
+		_ = cn.c.Close()
+	}
+}
+
+func (cn *conn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := cn.c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := WriteCommand(cn.w, args...); err != nil {
+		return nil, err
+	}
+
+	return ReadValue(cn.r)
+}
+
+func (cn *conn) expectOk(ctx context.Context, args ...string) error {
+	value, err := cn.do(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	if respErr, ok := value.(Error); ok {
+		return respErr
+	}
+
+	return nil
+}
+
+func asInt(value any) (int64, error) {
+	n, ok := value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrUnexpectedReply, value)
+	}
+
+	return n, nil
+}
+
+func formatMillis(ttl time.Duration) string {
+	return strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+}
+
+func (c *Client) CompareAndDelete(ctx context.Context, key string, value string) (bool, error) {
+	reply, err := c.Eval(ctx, CompareAndDeleteScript, []string{key}, value)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := asInt(reply)
+	return n > 0, err
+}
+
+func (c *Client) CompareAndPExpire(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	reply, err := c.Eval(ctx, CompareAndPExpireScript, []string{key}, value, formatMillis(ttl))
+	if err != nil {
+		return false, err
+	}
+
+	n, err := asInt(reply)
+	return n > 0, err
+}
@@ -0,0 +1,57 @@
+package resp_test
+
+import (
+	"context"
+	"errors"
+	"github.com/censoredgit/light/resp"
+	"github.com/censoredgit/light/resp/resptest"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	server, err := resptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	c := resp.NewClient(&resp.Config{Addr: server.Addr()})
+	defer c.Close()
+
+	ctx := context.Background()
+
+	if err = c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.SetPX(ctx, "key", []byte("multi\r\nline"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	value, found, err := c.Get(ctx, "key")
+	if err != nil || !found || string(value) != "multi\r\nline" {
+		t.Fatalf("unexpected get result %q %v %v", value, found, err)
+	}
+
+	if ok, _ := c.SetNXPX(ctx, "key", "other", time.Minute); ok {
+		t.Fatal("set nx should fail for existing key")
+	}
+
+	if ok, _ := c.CompareAndDelete(ctx, "key", "other"); ok {
+		t.Fatal("compare and delete should fail for other value")
+	}
+
+	if n, _ := c.Del(ctx, "key"); n != 1 {
+		t.Fatal("key should be deleted")
+	}
+
+	if _, found, _ = c.Get(ctx, "key"); found {
+		t.Fatal("key should not exist")
+	}
+
+	var respErr resp.Error
+	if _, err = c.Do(ctx, "UNKNOWN"); !errors.As(err, &respErr) {
+		t.Fatalf("expected resp error, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package resp
+
+import "time"
+
+type Config struct {
+	Addr        string
+	Password    string
+	DB          int
+	PoolSize    int
+	DialTimeout time.Duration
+}
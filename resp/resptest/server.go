@@ -0,0 +1,275 @@
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/censoredgit/light/resp"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type item struct {
+	value  string
+	expire time.Time
+}
+
+type Server struct {
+	listener net.Listener
+	lock     sync.Mutex
+	items    map[string]*item
+	wg       sync.WaitGroup
+}
+
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		items:    make(map[string]*item),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := 0
+	for key := range s.items {
+		if s.lookup(key) != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+
+	for {
+		value, err := resp.ReadValue(r)
+		if err != nil {
+			return
+		}
+
+		values, ok := value.([]any)
+		if !ok || len(values) == 0 {
+			writeError(w, "ERR protocol error")
+			continue
+		}
+
+		args := make([]string, len(values))
+		for i, v := range values {
+			b, _ := v.([]byte)
+			args[i] = string(b)
+		}
+
+		s.exec(w, args)
+
+		if err = w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, args []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "AUTH", "SELECT":
+		writeSimple(w, "OK")
+	case "GET":
+		if it := s.lookup(args[1]); it != nil {
+			writeBulk(w, it.value)
+		} else {
+			writeNil(w)
+		}
+	case "SET":
+		s.set(w, args[1:])
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if s.lookup(key) != nil {
+				delete(s.items, key)
+				n++
+			}
+		}
+		writeInt(w, n)
+	case "PEXPIRE":
+		ms, _ := strconv.ParseInt(args[2], 10, 64)
+		writeInt(w, s.pexpire(args[1], ms))
+	case "PTTL":
+		it := s.lookup(args[1])
+		switch {
+		case it == nil:
+			writeInt(w, -2)
+		case it.expire.IsZero():
+			writeInt(w, -1)
+		default:
+			writeInt(w, int(time.Until(it.expire).Milliseconds()))
+		}
+	case "EVAL":
+		s.eval(w, args[1:])
+	default:
+		writeError(w, "ERR unknown command '"+args[0]+"'")
+	}
+}
+
+func (s *Server) set(w *bufio.Writer, args []string) {
+	if len(args) < 2 {
+		writeError(w, "ERR wrong number of arguments for 'set' command")
+		return
+	}
+
+	it := &item{value: args[1]}
+	nx := false
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "PX", "EX":
+			if i+1 >= len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl := time.Duration(n) * time.Millisecond
+			if strings.ToUpper(args[i]) == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+			it.expire = time.Now().Add(ttl)
+			i++
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	if nx && s.lookup(args[0]) != nil {
+		writeNil(w)
+		return
+	}
+
+	s.items[args[0]] = it
+	writeSimple(w, "OK")
+}
+
+func (s *Server) pexpire(key string, ms int64) int {
+	it := s.lookup(key)
+	if it == nil {
+		return 0
+	}
+
+	it.expire = time.Now().Add(time.Duration(ms) * time.Millisecond)
+	return 1
+}
+
+func (s *Server) eval(w *bufio.Writer, args []string) {
+	if len(args) < 4 || args[1] != "1" {
+		writeError(w, "ERR unsupported script")
+		return
+	}
+
+	key, value := args[2], args[3]
+	it := s.lookup(key)
+	owned := it != nil && it.value == value
+
+	switch args[0] {
+	case resp.CompareAndDeleteScript:
+		if !owned {
+			writeInt(w, 0)
+			return
+		}
+		delete(s.items, key)
+		writeInt(w, 1)
+	case resp.CompareAndPExpireScript:
+		if !owned || len(args) < 5 {
+			writeInt(w, 0)
+			return
+		}
+		ms, _ := strconv.ParseInt(args[4], 10, 64)
+		writeInt(w, s.pexpire(key, ms))
+	default:
+		writeError(w, "ERR unsupported script")
+	}
+}
+
+func (s *Server) lookup(key string) *item {
+	it, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+
+	if !it.expire.IsZero() && it.expire.Before(time.Now()) {
+		delete(s.items, key)
+		return nil
+	}
+
+	return it
+}
+
+func writeSimple(w io.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func writeError(w io.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "-%s\r\n", s)
+}
+
+func writeInt(w io.Writer, n int) {
+	_, _ = fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeBulk(w io.Writer, s string) {
+	_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeNil(w io.Writer) {
+	_, _ = io.WriteString(w, "$-1\r\n")
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	JSONCodecName = "json"
+	GobCodecName  = "gob"
+)
+
+var ErrUnknownCodec = errors.New("unknown codec")
+
+type Codec interface {
+	Name() string
+	Encode(v any) (string, error)
+	Decode(s string, v any) error
+}
+
+var (
+	JSONCodec Codec = jsonCodec{}
+	GobCodec  Codec = gobCodec{}
+)
+
+var codecs = struct {
+	lock  sync.RWMutex
+	items map[string]Codec
+}{
+	items: map[string]Codec{
+		JSONCodecName: JSONCodec,
+		GobCodecName:  GobCodec,
+	},
+}
+
+func RegisterCodec(codec Codec) {
+	codecs.lock.Lock()
+	defer codecs.lock.Unlock()
+
+	codecs.items[codec.Name()] = codec
+}
+
+func LookupCodec(name string) (Codec, error) {
+	codecs.lock.RLock()
+	defer codecs.lock.RUnlock()
+
+	codec, ok := codecs.items[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+	}
+
+	return codec, nil
+}
+
+type jsonCodec struct{}
+
+func (c jsonCodec) Name() string {
+	return JSONCodecName
+}
+
+func (c jsonCodec) Encode(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (c jsonCodec) Decode(s string, v any) error {
+	return json.Unmarshal([]byte(s), v)
+}
+
+type gobCodec struct{}
+
+func (c gobCodec) Name() string {
+	return GobCodecName
+}
+
+func (c gobCodec) Encode(v any) (string, error) {
+	buf := bytes.NewBuffer([]byte{})
+
+	benc := base64.NewEncoder(base64.RawStdEncoding, buf)
+	if err := gob.NewEncoder(benc).Encode(v); err != nil {
+		return "", err
+	}
+
+	if err := benc.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (c gobCodec) Decode(s string, v any) error {
+	bdec := base64.NewDecoder(base64.RawStdEncoding, bytes.NewBufferString(s))
+	return gob.NewDecoder(bdec).Decode(v)
+}
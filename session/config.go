@@ -2,6 +2,7 @@ package session
 
 import (
 	"log/slog"
+	"net/http"
 	"time"
 )
 
@@ -12,4 +13,21 @@ type Config struct {
 	Driver     Driver
 	Logger     *slog.Logger
 	Hasher     Hasher
+	Codec      Codec
+
+	IdGenerator IdGenerator
+	SigningKeys [][]byte
+
+	CookiePath        string
+	CookieDomain      string
+	CookieSecure      bool
+	CookieHttpOnly    bool
+	CookieSameSite    http.SameSite
+	CookiePartitioned bool
+
+	// Sliding extends the session by TTL once half of it is used.
+	Sliding     bool
+	MaxLifetime time.Duration
+
+	Hooks Hooks
 }
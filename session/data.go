@@ -3,29 +3,57 @@ package session
 import "time"
 
 type Data struct {
-	id         string
-	data       map[string]string
-	expireTime time.Time
-	isModified bool
-	isNew      bool
+	id            string
+	data          map[string]string
+	encodings     map[string]string
+	codec         Codec
+	expireTime    time.Time
+	createdTime   time.Time
+	isModified    bool
+	isNew         bool
+	isInvalidated bool
+	renewCookie   bool
 }
 
 func NewData(id string, ttl time.Duration) *Data {
+	currentTime := time.Now()
 	return &Data{
-		id:         id,
-		data:       make(map[string]string),
-		expireTime: time.Now().Add(ttl),
-		isNew:      true,
-		isModified: true,
+		id:          id,
+		data:        make(map[string]string),
+		expireTime:  currentTime.Add(ttl),
+		createdTime: currentTime,
+		isNew:       true,
+		isModified:  true,
 	}
 }
 
 func NewExistsData(id string, ttl time.Duration) *Data {
+	currentTime := time.Now()
 	return &Data{
-		id:         id,
-		data:       make(map[string]string),
-		expireTime: time.Now().Add(ttl),
-		isNew:      false,
+		id:          id,
+		data:        make(map[string]string),
+		expireTime:  currentTime.Add(ttl),
+		createdTime: currentTime,
+		isNew:       false,
+	}
+}
+
+func RestoreData(id string, values map[string]string, expire time.Time, created time.Time) *Data {
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	data := make(map[string]string, len(values))
+	for k, v := range values {
+		data[k] = v
+	}
+
+	return &Data{
+		id:          id,
+		data:        data,
+		expireTime:  expire,
+		createdTime: created,
+		isNew:       false,
 	}
 }
 
@@ -52,11 +80,13 @@ func (d *Data) Has(key string) bool {
 
 func (d *Data) Set(key, value string) {
 	d.data[key] = value
+	delete(d.encodings, key)
 	d.isModified = true
 }
 
 func (d *Data) Delete(key string) {
 	delete(d.data, key)
+	delete(d.encodings, key)
 	d.isModified = true
 }
 
@@ -64,6 +94,10 @@ func (d *Data) Expire() time.Time {
 	return d.expireTime
 }
 
+func (d *Data) Created() time.Time {
+	return d.createdTime
+}
+
 func (d *Data) isExpired() bool {
 	return d.expireTime.Before(time.Now())
 }
@@ -77,6 +111,22 @@ func (d *Data) IsEmpty() bool {
 }
 func (d *Data) Empty() {
 	clear(d.data)
+	clear(d.encodings)
+}
+
+func (d *Data) Touch() {
+	d.isModified = true
+}
+
+func (d *Data) Invalidate() {
+	clear(d.data)
+	clear(d.encodings)
+	d.isInvalidated = true
+	d.isModified = true
+}
+
+func (d *Data) IsInvalidated() bool {
+	return d.isInvalidated
 }
 
 func (d *Data) OnSaved() {
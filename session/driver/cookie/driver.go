@@ -0,0 +1,155 @@
+package cookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/censoredgit/light/session"
+	"net/http"
+	"time"
+)
+
+const DefaultCookieName = "_session_data"
+const MaxCookieSize = 4096
+
+var ErrCookieTooLarge = errors.New("session cookie too large")
+var ErrNoKeys = errors.New("at least one key required")
+
+type driver struct {
+	cookieName string
+	keys       [][]byte
+	aeads      []cipher.AEAD
+	lifeTime   time.Duration
+}
+
+func Setup(
+	cookieName string,
+	keys [][]byte,
+	lifeTime time.Duration,
+) session.Driver {
+	return &driver{
+		cookieName: cookieName,
+		keys:       keys,
+		lifeTime:   lifeTime,
+	}
+}
+
+func (d *driver) Init() error {
+	if len(d.keys) == 0 {
+		return ErrNoKeys
+	}
+
+	d.aeads = make([]cipher.AEAD, 0, len(d.keys))
+	for _, key := range d.keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("session cookie driver init error: %w", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("session cookie driver init error: %w", err)
+		}
+
+		d.aeads = append(d.aeads, aead)
+	}
+
+	return nil
+}
+
+func (d *driver) Open(id string) (*session.Data, error) {
+	return session.NewData(id, d.lifeTime), nil
+}
+
+func (d *driver) OpenByRequest(id string, req *http.Request) (*session.Data, error) {
+	c, err := req.Cookie(d.cookieName)
+	if err != nil {
+		return session.NewData(id, d.lifeTime), nil
+	}
+
+	plain, keyIndex, err := d.decrypt(c.Value)
+	if err != nil {
+		return session.NewData(id, d.lifeTime), nil
+	}
+
+	data, err := session.Unmarshal(plain)
+	if err != nil || data.Id() != id {
+		return session.NewData(id, d.lifeTime), nil
+	}
+
+	if keyIndex > 0 {
+		data.Touch()
+	}
+
+	return data, nil
+}
+
+func (d *driver) Close(data *session.Data) error {
+	return nil
+}
+
+func (d *driver) Cookies(data *session.Data, template *http.Cookie) ([]*http.Cookie, error) {
+	if !data.IsNew() && !data.IsModified() && !data.IsInvalidated() {
+		return nil, nil
+	}
+
+	c := *template
+	c.Name = d.cookieName
+
+	if data.IsInvalidated() {
+		c.Value = ""
+		return []*http.Cookie{&c}, nil
+	}
+
+	plain, err := session.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Value, err = d.encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	if size := len(c.String()); size > MaxCookieSize {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d bytes", ErrCookieTooLarge, size, MaxCookieSize)
+	}
+
+	data.OnSaved()
+
+	return []*http.Cookie{&c}, nil
+}
+
+func (d *driver) encrypt(plain []byte) (string, error) {
+	aead := d.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(d.cookieName))), nil
+}
+
+func (d *driver) decrypt(value string) ([]byte, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, aead := range d.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(d.cookieName))
+		if err == nil {
+			return plain, i, nil
+		}
+	}
+
+	return nil, 0, errors.New("session cookie can not be decrypted")
+}
@@ -0,0 +1,106 @@
+package cookie
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupDriver(t *testing.T, keys ...[]byte) *driver {
+	d := Setup(DefaultCookieName, keys, time.Minute).(*driver)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func requestWith(c *http.Cookie) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(c)
+	return req
+}
+
+func TestDriver(t *testing.T) {
+	const sessId = "111"
+
+	oldKey := bytes.Repeat([]byte("o"), 32)
+	newKey := bytes.Repeat([]byte("n"), 32)
+
+	d := setupDriver(t, oldKey)
+
+	data, err := d.OpenByRequest(sessId, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+
+	cookies, err := d.Cookies(data, &http.Cookie{Path: "/", Expires: data.Expire()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || strings.Contains(cookies[0].Value, "test data") {
+		t.Fatal("encrypted cookie expected")
+	}
+
+	data, err = d.OpenByRequest(sessId, requestWith(cookies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.IsNew() || data.Get("test") != "test data" {
+		t.Fatal("sess cookie corrupted")
+	}
+
+	if unchanged, _ := d.Cookies(data, &http.Cookie{}); len(unchanged) != 0 {
+		t.Fatal("unmodified session should not be written")
+	}
+
+	rotated := setupDriver(t, newKey, oldKey)
+
+	data, err = rotated.OpenByRequest(sessId, requestWith(cookies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Get("test") != "test data" || !data.IsModified() {
+		t.Fatal("session encrypted by old key should be readable and re-encrypted")
+	}
+
+	encrypted, err := rotated.Cookies(data, &http.Cookie{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, keyIndex, err := rotated.decrypt(encrypted[0].Value); err != nil || keyIndex != 0 {
+		t.Fatal("session should be re-encrypted by the primary key")
+	}
+
+	if foreign, _ := rotated.OpenByRequest("222", requestWith(encrypted[0])); !foreign.IsNew() {
+		t.Fatal("payload bound to another id should be rejected")
+	}
+
+	tampered := *encrypted[0]
+	value := []byte(tampered.Value)
+	if value[len(value)/2] == 'A' {
+		value[len(value)/2] = 'B'
+	} else {
+		value[len(value)/2] = 'A'
+	}
+	tampered.Value = string(value)
+	if data, _ = rotated.OpenByRequest(sessId, requestWith(&tampered)); !data.IsNew() {
+		t.Fatal("tampered cookie should be rejected")
+	}
+}
+
+func TestDriverCookieTooLarge(t *testing.T) {
+	d := setupDriver(t, bytes.Repeat([]byte("k"), 16))
+
+	data, _ := d.Open("111")
+	data.Set("test", strings.Repeat("x", MaxCookieSize))
+
+	if _, err := d.Cookies(data, &http.Cookie{}); !errors.Is(err, ErrCookieTooLarge) {
+		t.Fatalf("expected ErrCookieTooLarge, got %v", err)
+	}
+}
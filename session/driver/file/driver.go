@@ -2,13 +2,13 @@ package file
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/censoredgit/light/locker"
 	"github.com/censoredgit/light/session"
-	"io"
 	"log/slog"
 	"os"
-	"path"
+	"slices"
 	"time"
 )
 
@@ -17,17 +17,18 @@ const DefaultGarbageListInitCap = 1000
 
 type driver struct {
 	path                 string
-	locker               *locker.Locker
+	locker               locker.SimpleLocker
 	log                  *slog.Logger
 	garbage              []string
 	lifeTime             time.Duration
 	garbageSchedulerTime time.Duration
 	garbageListInitCap   uint
+	events               *session.DriverEvents
 }
 
 func Setup(
 	path string,
-	locker *locker.Locker,
+	locker locker.SimpleLocker,
 	log *slog.Logger,
 	lifeTime time.Duration,
 	garbageSchedulerTime time.Duration,
@@ -44,6 +45,10 @@ func Setup(
 	}
 }
 
+func (d *driver) Observe(events *session.DriverEvents) {
+	d.events = events
+}
+
 func (d *driver) Init() error {
 	f, err := os.Stat(d.path)
 	if err != nil {
@@ -59,35 +64,29 @@ func (d *driver) Init() error {
 }
 
 func (d *driver) Open(id string) (*session.Data, error) {
+	start := time.Now()
 	err := d.locker.SimpleLock(id)
+	d.events.LockWaited(time.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("session open error: %w", err)
 	}
 
-	f, err := os.OpenFile(d.composeSessionFilePath(id), os.O_CREATE|os.O_RDWR, os.ModePerm)
-	if err != nil {
-		d.locker.ReleaseSimpleLock(id)
-		return nil, fmt.Errorf("session open error: %w", err)
+	data, err := d.load(id)
+	if errors.Is(err, os.ErrNotExist) {
+		return session.NewData(id, d.lifeTime), nil
 	}
-	defer f.Close()
-
-	fileData, err := io.ReadAll(f)
 	if err != nil {
+		d.locker.ReleaseSimpleLock(id)
 		return nil, fmt.Errorf("session open error: %w", err)
 	}
 
-	data, err := decode(id, string(fileData))
-	if err != nil {
-		return session.NewData(id, d.lifeTime), nil
-	}
-
 	return data, nil
 }
 
 func (d *driver) Close(data *session.Data) error {
 	var err error
 	if data.IsNew() || data.IsModified() {
-		err = writeSessionFile(d.composeSessionFilePath(data.Id()), data)
+		err = d.save(data)
 	}
 
 	d.locker.ReleaseSimpleLock(data.Id())
@@ -95,8 +94,114 @@ func (d *driver) Close(data *session.Data) error {
 	return err
 }
 
-func (d *driver) composeSessionFilePath(id string) string {
-	return path.Clean(path.Join(d.path, id) + ".json")
+func (d *driver) Destroy(id string) error {
+	err := d.locker.SimpleLock(id)
+	if err != nil {
+		return fmt.Errorf("session destroy error: %w", err)
+	}
+	defer d.locker.ReleaseSimpleLock(id)
+
+	if err = d.removeSessionFiles(id); err != nil {
+		return fmt.Errorf("session destroy error: %w", err)
+	}
+
+	return nil
+}
+
+func (d *driver) Touch(id string) error {
+	err := d.locker.SimpleLock(id)
+	if err != nil {
+		return fmt.Errorf("session touch error: %w", err)
+	}
+	defer d.locker.ReleaseSimpleLock(id)
+
+	data, err := d.load(id)
+	if err != nil {
+		return fmt.Errorf("session touch error: %w", err)
+	}
+
+	touched := session.RestoreData(id, data.All(), time.Now().Add(d.lifeTime), data.Created())
+	touched.RestoreEncodings(data.Encodings())
+
+	if err = d.save(touched); err != nil {
+		return fmt.Errorf("session touch error: %w", err)
+	}
+
+	return nil
+}
+
+func (d *driver) SessionsByOwner(owner string) ([]session.SessionInfo, error) {
+	currentTime := time.Now()
+	infos := make([]session.SessionInfo, 0)
+
+	err := d.walk(func(id string, filePath string, modTime time.Time) {
+		if id == "" {
+			return
+		}
+
+		fileData, err := os.ReadFile(filePath)
+		if err != nil {
+			return
+		}
+
+		data, err := decode(id, string(fileData))
+		if err != nil {
+			return
+		}
+
+		if info := data.Info(); info.Owner == owner && info.Expire.After(currentTime) {
+			infos = append(infos, info)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+func (d *driver) DestroyByOwner(owner string, keepIds ...string) error {
+	infos, err := d.SessionsByOwner(owner)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if slices.Contains(keepIds, info.Id) {
+			continue
+		}
+		if err = d.Destroy(info.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load reads and decodes the session file. Corrupted files are quarantined
+// and reported as missing, so the caller starts a fresh session.
+func (d *driver) load(id string) (*session.Data, error) {
+	filePath, fileData, err := d.readSessionFile(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decode(id, string(fileData))
+	if err != nil {
+		d.quarantine(filePath, err)
+		return nil, os.ErrNotExist
+	}
+
+	return data, nil
+}
+
+func (d *driver) save(data *session.Data) error {
+	strJson, err := encode(data)
+	if err != nil {
+		return err
+	}
+
+	return d.writeSessionFile(data.Id(), []byte(strJson))
 }
 
 func (d *driver) runGarbageScheduler() {
@@ -113,29 +218,28 @@ func (d *driver) runGarbageScheduler() {
 }
 
 func (d *driver) collectGarbage() error {
-	dirList, err := os.ReadDir(d.path)
-	if err != nil {
-		return err
-	}
-
 	currentTime := time.Now()
 
-	for _, f := range dirList {
-		if f.IsDir() {
-			continue
+	return d.walk(func(id string, filePath string, modTime time.Time) {
+		if id == "" {
+			if modTime.Add(d.lifeTime).Before(currentTime) {
+				if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					d.log.Warn(err.Error())
+				}
+			}
+			return
 		}
 
-		fi, err := f.Info()
+		fileData, err := os.ReadFile(filePath)
 		if err != nil {
-			continue
+			return
 		}
 
-		if fi.ModTime().Add(d.lifeTime).Before(currentTime) {
-			d.garbage = append(d.garbage, path.Join(d.path, f.Name()))
+		data, err := decode(id, string(fileData))
+		if err != nil || data.Expire().Before(currentTime) {
+			d.garbage = append(d.garbage, id)
 		}
-	}
-
-	return nil
+	})
 }
 
 func (d *driver) clearGarbage() {
@@ -143,33 +247,63 @@ func (d *driver) clearGarbage() {
 		return
 	}
 
-	var err error
-	for _, f := range d.garbage {
-		err = os.Remove(f)
-		if err != nil {
-			d.log.Warn(err.Error())
+	removed := 0
+	for _, id := range d.garbage {
+		if d.clearSession(id) {
+			removed++
 		}
 	}
+	d.events.Collected(removed)
 
 	d.garbage = make([]string, 0, d.garbageListInitCap)
 }
 
-func writeSessionFile(filePath string, data *session.Data) error {
-	strJson, err := encode(data)
+func (d *driver) clearSession(id string) bool {
+	if err := d.locker.SimpleLock(id); err != nil {
+		return false
+	}
+	defer d.locker.ReleaseSimpleLock(id)
+
+	data, err := d.load(id)
 	if err != nil {
-		return err
+		return false
+	}
+
+	if !data.Expire().Before(time.Now()) {
+		return false
 	}
 
-	return os.WriteFile(filePath, []byte(strJson), os.ModePerm)
+	if err = d.removeSessionFiles(id); err != nil {
+		d.log.Warn(err.Error())
+		return false
+	}
+
+	return true
 }
 
 func encode(sessionData *session.Data) (string, error) {
 	stc := struct {
-		Data   map[string]string `json:"data"`
-		Expire time.Time         `json:"expire"`
-	}{}
-	stc.Data = sessionData.All()
+		Data      map[string]string          `json:"data"`
+		Values    map[string]json.RawMessage `json:"values,omitempty"`
+		Encodings map[string]string          `json:"encodings,omitempty"`
+		Expire    time.Time                  `json:"expire"`
+		Created   time.Time                  `json:"created"`
+	}{
+		Data:      make(map[string]string),
+		Values:    make(map[string]json.RawMessage),
+		Encodings: sessionData.Encodings(),
+	}
 	stc.Expire = sessionData.Expire()
+	stc.Created = sessionData.Created()
+
+	for k, v := range sessionData.All() {
+		if stc.Encodings[k] == session.JSONCodecName && json.Valid([]byte(v)) {
+			stc.Values[k] = json.RawMessage(v)
+			delete(stc.Encodings, k)
+			continue
+		}
+		stc.Data[k] = v
+	}
 
 	strJson, err := json.Marshal(stc)
 	if err != nil {
@@ -181,8 +315,11 @@ func encode(sessionData *session.Data) (string, error) {
 
 func decode(id string, strJson string) (*session.Data, error) {
 	stc := struct {
-		Data   map[string]string `json:"data"`
-		Expire time.Time         `json:"expire"`
+		Data      map[string]string          `json:"data"`
+		Values    map[string]json.RawMessage `json:"values"`
+		Encodings map[string]string          `json:"encodings"`
+		Expire    time.Time                  `json:"expire"`
+		Created   time.Time                  `json:"created"`
 	}{
 		Data:   make(map[string]string),
 		Expire: time.Now(),
@@ -192,10 +329,20 @@ func decode(id string, strJson string) (*session.Data, error) {
 		return nil, err
 	}
 
-	sessionData := session.NewExistsData(id, stc.Expire.Sub(time.Now()))
+	if stc.Data == nil {
+		stc.Data = make(map[string]string)
+	}
+	if stc.Encodings == nil {
+		stc.Encodings = make(map[string]string)
+	}
 
-	for k, v := range stc.Data {
-		sessionData.Set(k, v)
+	for k, v := range stc.Values {
+		stc.Data[k] = string(v)
+		stc.Encodings[k] = session.JSONCodecName
 	}
-	return sessionData, nil
+
+	data := session.RestoreData(id, stc.Data, stc.Expire, stc.Created)
+	data.RestoreEncodings(stc.Encodings)
+
+	return data, nil
 }
@@ -1,9 +1,16 @@
 package file
 
 import (
+	"errors"
 	"github.com/censoredgit/light/locker"
+	"github.com/censoredgit/light/locker/flock"
+	"github.com/censoredgit/light/session"
+	"github.com/censoredgit/light/session/sessiontest"
+	"io"
 	"log/slog"
 	"os"
+	"path"
+	"strings"
 	"testing"
 	"time"
 )
@@ -11,7 +18,7 @@ import (
 func TestDriver(t *testing.T) {
 	const sessId = "111"
 
-	d := Setup(os.TempDir(),
+	d := Setup(t.TempDir(),
 		locker.New(&locker.Config{
 			GCTimeout: time.Second * 5,
 		}),
@@ -45,3 +52,278 @@ func TestDriver(t *testing.T) {
 		t.Error("sess file corrupted")
 	}
 }
+
+func TestDriverFormat(t *testing.T) {
+	legacy := `{"data":{"test":"test data"},"expire":"2030-01-01T00:00:00Z","created":"2020-01-01T00:00:00Z"}`
+
+	data, err := decode("111", legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Get("test") != "test data" || data.Encoding("test") != "" {
+		t.Error("legacy session file should be readable")
+	}
+
+	if err = session.SetAs(data, "typed", map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	strJson, err := encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strJson, `"values":{"typed":{"a":1}}`) {
+		t.Errorf("json value should be stored natively, got %s", strJson)
+	}
+
+	restored, err := decode("111", strJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typed, err := session.GetAs[map[string]int](restored, "typed")
+	if err != nil || typed["a"] != 1 {
+		t.Errorf("typed value should be restored, got %v %v", typed, err)
+	}
+	if restored.Get("test") != "test data" {
+		t.Error("plain value should be restored")
+	}
+}
+
+func TestDriverOwnerSessions(t *testing.T) {
+	d := Setup(t.TempDir(),
+		locker.New(&locker.Config{}),
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		})),
+		time.Minute,
+		DefaultGarbageSchedulerTime,
+		DefaultGarbageListInitCap,
+	)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		data, err := d.Open(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owner := "john"
+		if id == "3" {
+			owner = "jane"
+		}
+		data.SetOwner(owner, session.Metadata{IP: "127.0.0.1", UserAgent: "test", LastActivity: time.Now()})
+		if err = d.Close(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ownerDriver := d.(session.OwnerDriver)
+
+	infos, err := ownerDriver.SessionsByOwner("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Metadata.IP != "127.0.0.1" {
+		t.Fatalf("expected 2 sessions with metadata, got %+v", infos)
+	}
+
+	if err = d.(session.Toucher).Touch("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ownerDriver.DestroyByOwner("john", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, _ = ownerDriver.SessionsByOwner("john")
+	if len(infos) != 1 || infos[0].Id != "1" {
+		t.Fatalf("only kept session should remain, got %+v", infos)
+	}
+
+	if infos, _ = ownerDriver.SessionsByOwner("jane"); len(infos) != 1 {
+		t.Fatal("other owner sessions should remain")
+	}
+}
+
+func setupDriver(t *testing.T) *driver {
+	d := Setup(t.TempDir(),
+		locker.New(&locker.Config{}),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		time.Minute,
+		DefaultGarbageSchedulerTime,
+		DefaultGarbageListInitCap,
+	)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d.(*driver)
+}
+
+func TestDriverAtomicWrite(t *testing.T) {
+	d := setupDriver(t)
+
+	data, err := d.Open("111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(d.composeSessionFilePath("111"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != filePerm {
+		t.Errorf("session file permissions should be %o, got %o", filePerm, fi.Mode().Perm())
+	}
+
+	files, err := os.ReadDir(path.Dir(d.composeSessionFilePath("111")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("temp files should not be left, got %d files", len(files))
+	}
+}
+
+func TestDriverLegacyLayout(t *testing.T) {
+	d := setupDriver(t)
+
+	legacy := `{"data":{"test":"legacy"},"expire":"2100-01-01T00:00:00Z","created":"2020-01-01T00:00:00Z"}`
+	if err := os.WriteFile(d.composeLegacySessionFilePath("111"), []byte(legacy), filePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := d.Open("111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.IsNew() || data.Get("test") != "legacy" {
+		t.Fatal("legacy session file should be read")
+	}
+	data.Touch()
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(d.composeLegacySessionFilePath("111")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("legacy session file should be migrated")
+	}
+	if _, err = os.Stat(d.composeSessionFilePath("111")); err != nil {
+		t.Error("session file should be written to shard directory")
+	}
+}
+
+func TestDriverQuarantine(t *testing.T) {
+	d := setupDriver(t)
+
+	filePath := d.composeSessionFilePath("111")
+	if err := os.MkdirAll(path.Dir(filePath), dirPerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte(`{"data":{"tes`), filePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := d.Open("111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.IsNew() {
+		t.Error("corrupted session should be replaced by new one")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	quarantined, err := os.ReadDir(path.Join(d.path, quarantineDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 1 {
+		t.Errorf("corrupted file should be quarantined, got %d files", len(quarantined))
+	}
+}
+
+func TestDriverGarbage(t *testing.T) {
+	d := setupDriver(t)
+
+	for id, expire := range map[string]time.Time{
+		"expired": time.Now().Add(-time.Second),
+		"active":  time.Now().Add(time.Minute),
+	} {
+		data := session.RestoreData(id, map[string]string{"test": id}, expire, time.Now())
+		if err := d.save(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.collectGarbage(); err != nil {
+		t.Fatal(err)
+	}
+	d.clearGarbage()
+
+	if _, err := os.Stat(d.composeSessionFilePath("expired")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("expired session should be collected")
+	}
+	if _, err := os.Stat(d.composeSessionFilePath("active")); err != nil {
+		t.Error("active session should be kept")
+	}
+}
+
+func TestDriverConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		d := Setup(t.TempDir(),
+			locker.New(&locker.Config{}),
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+			time.Minute,
+			DefaultGarbageSchedulerTime,
+			DefaultGarbageListInitCap,
+		).(*driver)
+		return &sessiontest.Harness{
+			Driver: d,
+			CollectGarbage: func() {
+				if err := d.collectGarbage(); err != nil {
+					t.Fatal(err)
+				}
+				d.clearGarbage()
+			},
+			Corrupt: func(id string) {
+				if err := os.WriteFile(d.composeSessionFilePath(id), []byte(`{"data":`), filePerm); err != nil {
+					t.Fatal(err)
+				}
+			},
+		}
+	})
+}
+
+func TestDriverFlockConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		locks, err := flock.New(t.TempDir(), &flock.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := Setup(t.TempDir(),
+			locks,
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+			time.Minute,
+			DefaultGarbageSchedulerTime,
+			DefaultGarbageListInitCap,
+		).(*driver)
+		return &sessiontest.Harness{
+			Driver: d,
+			CollectGarbage: func() {
+				if err := d.collectGarbage(); err != nil {
+					t.Fatal(err)
+				}
+				d.clearGarbage()
+			},
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	sessionFileExt  = ".json"
+	tempFileExt     = ".tmp"
+	quarantineDir   = "quarantine"
+	shardNameLength = 2
+	filePerm        = 0o600
+	dirPerm         = 0o700
+)
+
+func (d *driver) composeSessionFilePath(id string) string {
+	return path.Join(d.path, shardName(id), id+sessionFileExt)
+}
+
+func (d *driver) composeLegacySessionFilePath(id string) string {
+	return path.Join(d.path, id+sessionFileExt)
+}
+
+// readSessionFile reads the session file from its shard directory and falls
+// back to the flat layout used by earlier versions.
+func (d *driver) readSessionFile(id string) (string, []byte, error) {
+	for _, filePath := range []string{d.composeSessionFilePath(id), d.composeLegacySessionFilePath(id)} {
+		fileData, err := os.ReadFile(filePath)
+		if err == nil {
+			return filePath, fileData, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return filePath, nil, err
+		}
+	}
+
+	return "", nil, os.ErrNotExist
+}
+
+func (d *driver) writeSessionFile(id string, fileData []byte) error {
+	filePath := d.composeSessionFilePath(id)
+	dir := path.Dir(filePath)
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, id+".*"+tempFileExt)
+	if err != nil {
+		return err
+	}
+	tempPath := f.Name()
+
+	if _, err = f.Write(fileData); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, filePerm)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	syncDir(dir)
+
+	if err = os.Remove(d.composeLegacySessionFilePath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		d.log.Warn(err.Error())
+	}
+
+	return nil
+}
+
+func (d *driver) removeSessionFiles(id string) error {
+	for _, filePath := range []string{d.composeSessionFilePath(id), d.composeLegacySessionFilePath(id)} {
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *driver) quarantine(filePath string, reason error) {
+	dir := path.Join(d.path, quarantineDir)
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		d.log.Error(err.Error())
+		return
+	}
+
+	target := path.Join(dir, fmt.Sprintf("%s.%d", path.Base(filePath), time.Now().UnixNano()))
+	if err := os.Rename(filePath, target); err != nil {
+		d.log.Error(err.Error())
+		return
+	}
+
+	d.log.Warn(fmt.Sprintf("session file %s corrupted, moved to %s: %s", filePath, target, reason.Error()))
+}
+
+// walk calls fn for every session file in the shard directories and in the
+// legacy flat layout. Temp files are passed with an empty id.
+func (d *driver) walk(fn func(id string, filePath string, modTime time.Time)) error {
+	dirList, err := os.ReadDir(d.path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range dirList {
+		if !entry.IsDir() {
+			d.visit(d.path, entry, fn)
+			continue
+		}
+
+		if len(entry.Name()) != shardNameLength {
+			continue
+		}
+
+		shardPath := path.Join(d.path, entry.Name())
+		shardList, err := os.ReadDir(shardPath)
+		if err != nil {
+			d.log.Warn(err.Error())
+			continue
+		}
+
+		for _, f := range shardList {
+			if !f.IsDir() {
+				d.visit(shardPath, f, fn)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *driver) visit(dir string, entry os.DirEntry, fn func(id string, filePath string, modTime time.Time)) {
+	fi, err := entry.Info()
+	if err != nil {
+		return
+	}
+
+	filePath := path.Join(dir, entry.Name())
+
+	if strings.HasSuffix(entry.Name(), tempFileExt) {
+		fn("", filePath, fi.ModTime())
+		return
+	}
+
+	if id, ok := strings.CutSuffix(entry.Name(), sessionFileExt); ok {
+		fn(id, filePath, fi.ModTime())
+	}
+}
+
+func shardName(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])[:shardNameLength]
+}
+
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_ = f.Sync()
+}
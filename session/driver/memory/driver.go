@@ -1,22 +1,65 @@
 package memory
 
 import (
+	"container/list"
 	"fmt"
 	"github.com/censoredgit/light/locker"
 	"github.com/censoredgit/light/session"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const DefaultGarbageSchedulerTime = 60 * time.Minute
 const DefaultGarbageListInitCap = 1000
+const DefaultShardCount = 32
+
+type Option func(d *driver)
+
+// WithMaxSessions limits the number of stored sessions. Every shard keeps
+// its share of the limit and evicts its least recently used sessions.
+func WithMaxSessions(n uint) Option {
+	return func(d *driver) {
+		d.maxSessions = n
+	}
+}
+
+func WithShardCount(n uint) Option {
+	return func(d *driver) {
+		if n > 0 {
+			d.shardCount = n
+		}
+	}
+}
+
+type entry struct {
+	id        string
+	values    map[string]string
+	encodings map[string]string
+	expire    time.Time
+	created   time.Time
+	info      session.SessionInfo
+}
+
+type shard struct {
+	lock     sync.Mutex
+	items    map[string]*list.Element
+	lru      *list.List
+	capacity int
+}
 
 type driver struct {
 	locker               *locker.Locker
 	lifeTime             time.Duration
 	garbageSchedulerTime time.Duration
 	garbageListInitCap   uint
-	garbage              []string
-	storage              map[string]*session.Data
+	maxSessions          uint
+	shardCount           uint
+	shards               []*shard
+	count                atomic.Int64
+	events               *session.DriverEvents
 }
 
 func Setup(
@@ -24,15 +67,40 @@ func Setup(
 	lifeTime time.Duration,
 	garbageSchedulerTime time.Duration,
 	garbageListInitCap uint,
+	opts ...Option,
 ) session.Driver {
-	return &driver{
+	d := &driver{
 		locker:               locker,
 		lifeTime:             lifeTime,
 		garbageSchedulerTime: garbageSchedulerTime,
 		garbageListInitCap:   garbageListInitCap,
-		garbage:              make([]string, garbageListInitCap),
-		storage:              make(map[string]*session.Data),
+		shardCount:           DefaultShardCount,
 	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	capacity := 0
+	if d.maxSessions > 0 {
+		d.shardCount = min(d.shardCount, d.maxSessions)
+		capacity = int((d.maxSessions + d.shardCount - 1) / d.shardCount)
+	}
+
+	d.shards = make([]*shard, d.shardCount)
+	for i := range d.shards {
+		d.shards[i] = &shard{
+			items:    make(map[string]*list.Element),
+			lru:      list.New(),
+			capacity: capacity,
+		}
+	}
+
+	return d
+}
+
+func (d *driver) Observe(events *session.DriverEvents) {
+	d.events = events
 }
 
 func (d *driver) Init() error {
@@ -41,23 +109,30 @@ func (d *driver) Init() error {
 }
 
 func (d *driver) Open(id string) (*session.Data, error) {
+	start := time.Now()
 	err := d.locker.SimpleLock(id)
+	d.events.LockWaited(time.Since(start))
 	if err != nil {
 		return nil, fmt.Errorf("session open error: %w", err)
 	}
 
-	if data, exists := d.storage[id]; exists {
-		return data, nil
+	s := d.shard(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, exists := s.items[id]
+	if !exists {
+		return session.NewData(id, d.lifeTime), nil
 	}
 
-	data := session.NewData(id, d.lifeTime)
-	d.storage[id] = data
-	return data, nil
+	s.lru.MoveToFront(el)
+
+	return el.Value.(*entry).data(), nil
 }
 
 func (d *driver) Close(data *session.Data) error {
 	if data.IsNew() || data.IsModified() {
-		d.storage[data.Id()] = data
+		d.store(data)
 	}
 
 	d.locker.ReleaseSimpleLock(data.Id())
@@ -65,39 +140,188 @@ func (d *driver) Close(data *session.Data) error {
 	return nil
 }
 
+func (d *driver) Destroy(id string) error {
+	err := d.locker.SimpleLock(id)
+	if err != nil {
+		return fmt.Errorf("session destroy error: %w", err)
+	}
+	defer d.locker.ReleaseSimpleLock(id)
+
+	s := d.shard(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if el, exists := s.items[id]; exists {
+		d.remove(s, el)
+	}
+
+	return nil
+}
+
+func (d *driver) Touch(id string) error {
+	err := d.locker.SimpleLock(id)
+	if err != nil {
+		return fmt.Errorf("session touch error: %w", err)
+	}
+	defer d.locker.ReleaseSimpleLock(id)
+
+	s := d.shard(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	el, exists := s.items[id]
+	if !exists {
+		return fmt.Errorf("session touch error: %w: %s", session.ErrNotExists, id)
+	}
+
+	e := el.Value.(*entry)
+	e.expire = time.Now().Add(d.lifeTime)
+	e.info.Expire = e.expire
+	s.lru.MoveToFront(el)
+
+	return nil
+}
+
+func (d *driver) SessionsByOwner(owner string) ([]session.SessionInfo, error) {
+	currentTime := time.Now()
+	infos := make([]session.SessionInfo, 0)
+
+	for _, s := range d.shards {
+		s.lock.Lock()
+		for _, el := range s.items {
+			e := el.Value.(*entry)
+			if e.info.Owner == owner && e.expire.After(currentTime) {
+				infos = append(infos, e.info)
+			}
+		}
+		s.lock.Unlock()
+	}
+
+	return infos, nil
+}
+
+func (d *driver) DestroyByOwner(owner string, keepIds ...string) error {
+	infos, err := d.SessionsByOwner(owner)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if slices.Contains(keepIds, info.Id) {
+			continue
+		}
+		if err = d.Destroy(info.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *driver) len() int {
+	return int(d.count.Load())
+}
+
+func (d *driver) store(data *session.Data) {
+	e := newEntry(data)
+
+	s := d.shard(e.id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if el, exists := s.items[e.id]; exists {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return
+	}
+
+	s.items[e.id] = s.lru.PushFront(e)
+	d.count.Add(1)
+
+	for s.capacity > 0 && s.lru.Len() > s.capacity {
+		d.remove(s, s.lru.Back())
+	}
+}
+
+func (d *driver) remove(s *shard, el *list.Element) {
+	delete(s.items, el.Value.(*entry).id)
+	s.lru.Remove(el)
+	d.count.Add(-1)
+}
+
+func (d *driver) shard(id string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+
+	return d.shards[h.Sum32()%uint32(len(d.shards))]
+}
+
 func (d *driver) runGarbageScheduler() {
 	for {
 		time.Sleep(d.garbageSchedulerTime)
 
-		d.collectGarbage()
-		d.clearGarbage()
+		d.clearGarbage(d.collectGarbage())
 	}
 }
 
-func (d *driver) collectGarbage() {
+func (d *driver) collectGarbage() []string {
 	currentTime := time.Now()
-	var unlocker locker.ReadUnlocker
+	garbage := make([]string, 0, d.garbageListInitCap)
 
-	for id, data := range d.storage {
-		unlocker = d.locker.ReadLock(id)
-		if data.Expire().Before(currentTime) {
-			d.garbage = append(d.garbage, id)
+	for _, s := range d.shards {
+		s.lock.Lock()
+		for id, el := range s.items {
+			if el.Value.(*entry).expire.Before(currentTime) {
+				garbage = append(garbage, id)
+			}
 		}
-		unlocker.RUnlock()
+		s.lock.Unlock()
 	}
+
+	return garbage
 }
 
-func (d *driver) clearGarbage() {
-	if len(d.garbage) == 0 {
-		return
+func (d *driver) clearGarbage(garbage []string) {
+	currentTime := time.Now()
+	removed := 0
+
+	for _, id := range garbage {
+		s := d.shard(id)
+		s.lock.Lock()
+		if el, exists := s.items[id]; exists && el.Value.(*entry).expire.Before(currentTime) {
+			d.remove(s, el)
+			removed++
+		}
+		s.lock.Unlock()
+	}
+
+	d.events.Collected(removed)
+}
+
+func newEntry(data *session.Data) *entry {
+	values := make(map[string]string, len(data.All()))
+	for k, v := range data.All() {
+		values[k] = v
 	}
 
-	var unlocker locker.WriteUnlocker
-	for _, id := range d.garbage {
-		unlocker = d.locker.WriteLock(id)
-		delete(d.storage, id)
-		unlocker.Unlock()
+	e := &entry{
+		id:        data.Id(),
+		values:    values,
+		encodings: data.Encodings(),
+		expire:    data.Expire(),
+		created:   data.Created(),
 	}
 
-	d.garbage = make([]string, 0, d.garbageListInitCap)
+	if info := data.Info(); info.Owner != "" {
+		e.info = info
+	}
+
+	return e
+}
+
+func (e *entry) data() *session.Data {
+	data := session.RestoreData(e.id, e.values, e.expire, e.created)
+	data.RestoreEncodings(e.encodings)
+
+	return data
 }
@@ -1,7 +1,15 @@
 package memory
 
 import (
+	"fmt"
 	"github.com/censoredgit/light/locker"
+	"github.com/censoredgit/light/session"
+	"github.com/censoredgit/light/session/sessiontest"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -43,3 +51,353 @@ func TestDriver(t *testing.T) {
 	}
 
 }
+
+func TestDriverOwnerSessions(t *testing.T) {
+	d := Setup(locker.New(&locker.Config{}), time.Minute, DefaultGarbageSchedulerTime, DefaultGarbageListInitCap)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2", "3"} {
+		data, err := d.Open(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owner := "john"
+		if id == "3" {
+			owner = "jane"
+		}
+		data.SetOwner(owner, session.Metadata{IP: "127.0.0.1", UserAgent: "test", LastActivity: time.Now()})
+		if err = d.Close(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ownerDriver := d.(session.OwnerDriver)
+
+	infos, err := ownerDriver.SessionsByOwner("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Metadata.UserAgent != "test" {
+		t.Fatalf("expected 2 sessions with metadata, got %+v", infos)
+	}
+
+	if err = d.(session.Toucher).Touch("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ownerDriver.DestroyByOwner("john", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, _ = ownerDriver.SessionsByOwner("john")
+	if len(infos) != 1 || infos[0].Id != "1" {
+		t.Fatalf("only kept session should remain, got %+v", infos)
+	}
+
+	if infos, _ = ownerDriver.SessionsByOwner("jane"); len(infos) != 1 {
+		t.Fatal("other owner sessions should remain")
+	}
+}
+
+func setupDriver(t testing.TB, opts ...Option) *driver {
+	d := Setup(locker.New(&locker.Config{}), time.Minute, DefaultGarbageSchedulerTime, DefaultGarbageListInitCap, opts...)
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d.(*driver)
+}
+
+func TestDriverCopies(t *testing.T) {
+	d := setupDriver(t)
+
+	data, err := d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "stored")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	data.Set("test", "leaked")
+
+	data, err = d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "aborted")
+	data.OnSaved()
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close(data)
+
+	if data.Get("test") != "stored" {
+		t.Errorf("only closed changes should be stored, got %q", data.Get("test"))
+	}
+}
+
+func TestDriverTouchLocks(t *testing.T) {
+	d := setupDriver(t)
+
+	data, err := d.Open("touch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = d.Open("touch"); err != nil {
+		t.Fatal(err)
+	}
+
+	touched := make(chan error, 1)
+	go func() {
+		touched <- d.Touch("touch")
+	}()
+
+	select {
+	case <-touched:
+		t.Fatal("touch should wait for the open session")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-touched; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriverEviction(t *testing.T) {
+	d := setupDriver(t, WithMaxSessions(2), WithShardCount(1))
+
+	for _, id := range []string{"1", "2", "3"} {
+		data, err := d.Open(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "3" {
+			reopened, err := d.Open("1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = d.Close(reopened)
+		}
+		if err = d.Close(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if d.len() != 2 {
+		t.Fatalf("expected 2 sessions, got %d", d.len())
+	}
+
+	for id, exists := range map[string]bool{"1": true, "2": false, "3": true} {
+		if _, ok := d.shard(id).items[id]; ok != exists {
+			t.Errorf("session %s exists should be %v", id, exists)
+		}
+	}
+}
+
+func TestDriverGarbage(t *testing.T) {
+	d := setupDriver(t)
+
+	data, err := d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := session.RestoreData("1", data.All(), time.Now().Add(-time.Second), data.Created())
+	expired.Touch()
+	if err = d.Close(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	d.clearGarbage(d.collectGarbage())
+
+	if d.len() != 0 {
+		t.Errorf("expired session should be collected, %d sessions left", d.len())
+	}
+}
+
+func TestDriverConcurrent(t *testing.T) {
+	d := setupDriver(t, WithMaxSessions(64))
+
+	wg := sync.WaitGroup{}
+	for worker := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				id := fmt.Sprintf("%d-%d", worker, i%8)
+				data, err := d.Open(id)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err = data.Increment("counter", 1); err != nil {
+					t.Error(err)
+				}
+				if err = d.Close(data); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			d.clearGarbage(d.collectGarbage())
+			_, _ = d.SessionsByOwner("nobody")
+		}
+	}()
+
+	wg.Wait()
+
+	if d.len() > 64 {
+		t.Errorf("session count should be bounded, got %d", d.len())
+	}
+}
+
+func BenchmarkDriverParallel(b *testing.B) {
+	d := setupDriver(b, WithMaxSessions(100_000))
+
+	b.RunParallel(func(pb *testing.PB) {
+		id := strconv.FormatUint(rand.Uint64(), 36)
+		for pb.Next() {
+			data, err := d.Open(id)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			data.Set("test", "test data")
+			_ = d.Close(data)
+		}
+	})
+}
+
+func BenchmarkDriverParallelSessions(b *testing.B) {
+	d := setupDriver(b, WithMaxSessions(10_000))
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			data, err := d.Open(strconv.FormatUint(rand.Uint64N(1_000_000), 36))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			data.Set("test", "test data")
+			_ = d.Close(data)
+		}
+	})
+}
+
+func TestDriverEncrypted(t *testing.T) {
+	d := session.NewEncryptedDriver(setupDriver(t), slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("0123456789abcdef0123456789abcdef"))
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		data, err := d.Open(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.SetOwner("john", session.Metadata{UserAgent: "test"})
+		if err = d.Close(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err := d.SessionsByOwner("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Owner != "john" || infos[0].Metadata.UserAgent != "test" {
+		t.Fatalf("owner sessions should be listed through encrypted driver, got %+v", infos)
+	}
+
+	if err = d.DestroyByOwner("john", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if infos, _ = d.SessionsByOwner("john"); len(infos) != 1 || infos[0].Id != "1" {
+		t.Fatalf("only kept session should remain, got %+v", infos)
+	}
+
+	data, err := d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close(data)
+	if data.Owner() != "john" {
+		t.Error("owner should be decrypted")
+	}
+}
+
+func TestDriverConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		d := Setup(locker.New(&locker.Config{}), time.Minute, DefaultGarbageSchedulerTime, DefaultGarbageListInitCap).(*driver)
+		return &sessiontest.Harness{
+			Driver: d,
+			CollectGarbage: func() {
+				d.clearGarbage(d.collectGarbage())
+			},
+		}
+	})
+}
+
+func TestEncryptedDriverConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		d := Setup(locker.New(&locker.Config{}), time.Minute, DefaultGarbageSchedulerTime, DefaultGarbageListInitCap).(*driver)
+		return &sessiontest.Harness{
+			Driver: session.NewEncryptedDriver(d, slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("0123456789abcdef0123456789abcdef")),
+			CollectGarbage: func() {
+				d.clearGarbage(d.collectGarbage())
+			},
+		}
+	})
+}
+
+func TestDriverStats(t *testing.T) {
+	var collected int
+	d := Setup(locker.New(&locker.Config{}), time.Minute, DefaultGarbageSchedulerTime, DefaultGarbageListInitCap).(*driver)
+	m := session.MustSetup(&session.Config{
+		TTL:         time.Minute,
+		CookieName:  "test",
+		Driver:      d,
+		IdGenerator: session.NewSecureIdGenerator(0),
+		Hooks: session.Hooks{
+			OnCollected: func(count int) { collected += count },
+		},
+	})
+
+	data, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := session.RestoreData(data.Id(), map[string]string{"a": "1"}, time.Now().Add(-time.Second), data.Created())
+	expired.Touch()
+	if err = m.Close(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	d.clearGarbage(d.collectGarbage())
+
+	stats := m.Stats()
+	if stats.LockWaits != 1 || stats.Collected != 1 || collected != 1 {
+		t.Errorf("unexpected stats %+v, collected %d", stats, collected)
+	}
+}
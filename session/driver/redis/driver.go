@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	redislock "github.com/censoredgit/light/locker/redis"
+	"github.com/censoredgit/light/resp"
+	"github.com/censoredgit/light/session"
+	"log/slog"
+	"time"
+)
+
+const DefaultPrefix = "session:"
+const DefaultTimeout = 3 * time.Second
+
+type driver struct {
+	client   *resp.Client
+	locker   *redislock.Locker
+	prefix   string
+	log      *slog.Logger
+	lifeTime time.Duration
+	events   *session.DriverEvents
+}
+
+func Setup(
+	client *resp.Client,
+	locker *redislock.Locker,
+	prefix string,
+	log *slog.Logger,
+	lifeTime time.Duration,
+) session.Driver {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	return &driver{
+		client:   client,
+		locker:   locker,
+		prefix:   prefix,
+		log:      log.With(slog.String("session.driver", "redis")),
+		lifeTime: lifeTime,
+	}
+}
+
+func (d *driver) Observe(events *session.DriverEvents) {
+	d.events = events
+}
+
+func (d *driver) Init() error {
+	if d.client == nil {
+		return errors.New("client required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	return d.client.Ping(ctx)
+}
+
+func (d *driver) Open(id string) (*session.Data, error) {
+	if d.locker != nil {
+		start := time.Now()
+		err := d.locker.SimpleLock(id)
+		d.events.LockWaited(time.Since(start))
+		if err != nil {
+			return nil, fmt.Errorf("session open error: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	raw, found, err := d.client.Get(ctx, d.prefix+id)
+	if err != nil {
+		d.release(id)
+		return nil, fmt.Errorf("session open error: %w", err)
+	}
+
+	if !found {
+		return session.NewData(id, d.lifeTime), nil
+	}
+
+	data, err := session.Unmarshal(raw)
+	if err != nil || data.Id() != id {
+		d.log.Warn(fmt.Sprintf("session %s data corrupted", id))
+		return session.NewData(id, d.lifeTime), nil
+	}
+
+	return data, nil
+}
+
+func (d *driver) Close(data *session.Data) error {
+	defer d.release(data.Id())
+
+	if !data.IsNew() && !data.IsModified() {
+		return nil
+	}
+
+	ttl := time.Until(data.Expire())
+	if ttl <= 0 {
+		return d.Destroy(data.Id())
+	}
+
+	raw, err := session.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("session close error: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if err = d.client.SetPX(ctx, d.prefix+data.Id(), raw, ttl); err != nil {
+		return fmt.Errorf("session close error: %w", err)
+	}
+
+	data.OnSaved()
+
+	return nil
+}
+
+func (d *driver) Destroy(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if _, err := d.client.Del(ctx, d.prefix+id); err != nil {
+		return fmt.Errorf("session destroy error: %w", err)
+	}
+
+	return nil
+}
+
+func (d *driver) release(id string) {
+	if d.locker != nil {
+		d.locker.ReleaseSimpleLock(id)
+	}
+}
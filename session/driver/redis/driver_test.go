@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	redislock "github.com/censoredgit/light/locker/redis"
+	"github.com/censoredgit/light/resp"
+	"github.com/censoredgit/light/resp/resptest"
+	"github.com/censoredgit/light/session/sessiontest"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDriver(t *testing.T) {
+	const sessId = "111"
+
+	server, err := resptest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }()
+
+	client := resp.NewClient(&resp.Config{Addr: server.Addr()})
+	d := Setup(
+		client,
+		redislock.New(client, &redislock.Config{}),
+		DefaultPrefix,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		time.Minute,
+	)
+
+	if err = d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := d.Open(sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, err := client.Do(context.Background(), "PTTL", DefaultPrefix+sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := ttl.(int64); ms <= 0 || ms > time.Minute.Milliseconds() {
+		t.Errorf("session key ttl should follow session lifetime, got %dms", ms)
+	}
+
+	data, err = d.Open(sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.IsNew() || data.Get("test") != "test data" {
+		t.Error("session data should be restored")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.(interface{ Destroy(string) error }).Destroy(sessId); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = d.Open(sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.IsNew() {
+		t.Error("destroyed session should be new")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriverConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		server, err := resptest.NewServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = server.Close() })
+
+		client := resp.NewClient(&resp.Config{Addr: server.Addr()})
+		return &sessiontest.Harness{
+			Driver: Setup(
+				client,
+				redislock.New(client, &redislock.Config{RetryTimeout: time.Millisecond * 5}),
+				DefaultPrefix,
+				slog.New(slog.NewTextHandler(io.Discard, nil)),
+				time.Minute,
+			),
+			Corrupt: func(id string) {
+				if err := client.SetPX(context.Background(), DefaultPrefix+id, []byte("{"), time.Minute); err != nil {
+					t.Fatal(err)
+				}
+			},
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Dialect struct {
+	name        string
+	placeholder func(index int) string
+	upsert      func(s Schema, placeholders []string) string
+}
+
+var Postgres = Dialect{
+	name: "postgres",
+	placeholder: func(index int) string {
+		return fmt.Sprintf("$%d", index)
+	},
+	upsert: func(s Schema, placeholders []string) string {
+		return fmt.Sprintf(
+			"INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (%s) "+
+				"ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s "+
+				"WHERE %s.%s = EXCLUDED.%s - 1",
+			s.Table, s.IdColumn, s.DataColumn, s.ExpireColumn, s.CreatedColumn, s.VersionColumn,
+			strings.Join(placeholders, ", "),
+			s.IdColumn,
+			s.DataColumn, s.DataColumn,
+			s.ExpireColumn, s.ExpireColumn,
+			s.VersionColumn, s.VersionColumn,
+			s.Table, s.VersionColumn, s.VersionColumn,
+		)
+	},
+}
+
+var SQLite = Dialect{
+	name: "sqlite",
+	placeholder: func(index int) string {
+		return "?"
+	},
+	upsert: func(s Schema, placeholders []string) string {
+		return fmt.Sprintf(
+			"INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (%s) "+
+				"ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s, %s = excluded.%s, %s = excluded.%s "+
+				"WHERE %s.%s = excluded.%s - 1",
+			s.Table, s.IdColumn, s.DataColumn, s.ExpireColumn, s.CreatedColumn, s.VersionColumn,
+			strings.Join(placeholders, ", "),
+			s.IdColumn,
+			s.DataColumn, s.DataColumn,
+			s.ExpireColumn, s.ExpireColumn,
+			s.VersionColumn, s.VersionColumn,
+			s.Table, s.VersionColumn, s.VersionColumn,
+		)
+	},
+}
+
+var MySQL = Dialect{
+	name: "mysql",
+	placeholder: func(index int) string {
+		return "?"
+	},
+	upsert: func(s Schema, placeholders []string) string {
+		return fmt.Sprintf(
+			"INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (%s) "+
+				"ON DUPLICATE KEY UPDATE "+
+				"%s = IF(%s = VALUES(%s) - 1, VALUES(%s), %s), "+
+				"%s = IF(%s = VALUES(%s) - 1, VALUES(%s), %s), "+
+				"%s = IF(%s = VALUES(%s) - 1, VALUES(%s), %s)",
+			s.Table, s.IdColumn, s.DataColumn, s.ExpireColumn, s.CreatedColumn, s.VersionColumn,
+			strings.Join(placeholders, ", "),
+			s.DataColumn, s.VersionColumn, s.VersionColumn, s.DataColumn, s.DataColumn,
+			s.ExpireColumn, s.VersionColumn, s.VersionColumn, s.ExpireColumn, s.ExpireColumn,
+			s.VersionColumn, s.VersionColumn, s.VersionColumn, s.VersionColumn, s.VersionColumn,
+		)
+	},
+}
+
+func (d Dialect) Name() string {
+	return d.name
+}
+
+func (d Dialect) placeholders(from, count int) []string {
+	placeholders := make([]string, count)
+	for i := range count {
+		placeholders[i] = d.placeholder(from + i)
+	}
+
+	return placeholders
+}
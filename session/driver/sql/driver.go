@@ -0,0 +1,194 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/censoredgit/light/session"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+	"weak"
+)
+
+const DefaultGarbageSchedulerTime = 60 * time.Minute
+
+var ErrConflict = errors.New("session modified concurrently")
+
+type driver struct {
+	db                   *sql.DB
+	dialect              Dialect
+	schema               Schema
+	log                  *slog.Logger
+	lifeTime             time.Duration
+	garbageSchedulerTime time.Duration
+	selectQuery          string
+	upsertQuery          string
+	deleteQuery          string
+	gcQuery              string
+	events               *session.DriverEvents
+	// versions holds the row version of open sessions. It is keyed by weak
+	// pointers, so a session opened and never closed does not stay in it.
+	versions sync.Map
+}
+
+func Setup(
+	db *sql.DB,
+	dialect Dialect,
+	schema Schema,
+	log *slog.Logger,
+	lifeTime time.Duration,
+	garbageSchedulerTime time.Duration,
+) session.Driver {
+	return &driver{
+		db:                   db,
+		dialect:              dialect,
+		schema:               schema,
+		log:                  log.With(slog.String("session.driver", "sql")),
+		lifeTime:             lifeTime,
+		garbageSchedulerTime: garbageSchedulerTime,
+	}
+}
+
+func (d *driver) Observe(events *session.DriverEvents) {
+	d.events = events
+}
+
+func (d *driver) Init() error {
+	if d.db == nil {
+		return errors.New("db required")
+	}
+
+	if err := d.schema.validate(); err != nil {
+		return err
+	}
+
+	s := d.schema
+	p := d.dialect.placeholder
+
+	d.selectQuery = fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s WHERE %s = %s",
+		s.DataColumn, s.ExpireColumn, s.CreatedColumn, s.VersionColumn, s.Table, s.IdColumn, p(1))
+	d.upsertQuery = d.dialect.upsert(s, d.dialect.placeholders(1, 5))
+	d.deleteQuery = fmt.Sprintf("DELETE FROM %s WHERE %s = %s", s.Table, s.IdColumn, p(1))
+	d.gcQuery = fmt.Sprintf("DELETE FROM %s WHERE %s < %s", s.Table, s.ExpireColumn, p(1))
+
+	if d.garbageSchedulerTime > 0 {
+		go d.runGarbageScheduler()
+	}
+
+	return nil
+}
+
+func (d *driver) Open(id string) (*session.Data, error) {
+	var rawData string
+	var expire, created, version int64
+
+	err := d.db.QueryRow(d.selectQuery, id).Scan(&rawData, &expire, &created, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session.NewData(id, d.lifeTime), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session open error: %w", err)
+	}
+
+	data, err := decode(id, rawData, expire, created)
+	if err != nil {
+		d.log.Warn(fmt.Sprintf("session %s data corrupted: %s", id, err.Error()))
+		data = session.RestoreData(id, nil, time.UnixMilli(expire), time.UnixMilli(created))
+	}
+
+	key := weak.Make(data)
+	d.versions.Store(key, version)
+	runtime.AddCleanup(data, func(key weak.Pointer[session.Data]) {
+		d.versions.Delete(key)
+	}, key)
+
+	return data, nil
+}
+
+// decode reads the session.Marshal payload, the id and the times are taken
+// from the row columns.
+func decode(id string, rawData string, expire, created int64) (*session.Data, error) {
+	stored, err := session.Unmarshal([]byte(rawData))
+	if err != nil {
+		return nil, err
+	}
+
+	data := session.RestoreData(id, stored.All(), time.UnixMilli(expire), time.UnixMilli(created))
+	data.RestoreEncodings(stored.Encodings())
+
+	return data, nil
+}
+
+func (d *driver) Close(data *session.Data) error {
+	var version int64
+	if loaded, ok := d.versions.LoadAndDelete(weak.Make(data)); ok {
+		version = loaded.(int64)
+	}
+
+	if !data.IsNew() && !data.IsModified() {
+		return nil
+	}
+
+	rawData, err := session.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("session close error: %w", err)
+	}
+
+	result, err := d.db.Exec(
+		d.upsertQuery,
+		data.Id(),
+		string(rawData),
+		data.Expire().UnixMilli(),
+		data.Created().UnixMilli(),
+		version+1,
+	)
+	if err != nil {
+		return fmt.Errorf("session close error: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("session close error: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("session close error: %w", ErrConflict)
+	}
+
+	data.OnSaved()
+
+	return nil
+}
+
+func (d *driver) Destroy(id string) error {
+	if _, err := d.db.Exec(d.deleteQuery, id); err != nil {
+		return fmt.Errorf("session destroy error: %w", err)
+	}
+
+	return nil
+}
+
+func (d *driver) runGarbageScheduler() {
+	for {
+		time.Sleep(d.garbageSchedulerTime)
+
+		if err := d.collectGarbage(); err != nil {
+			d.log.Error(err.Error())
+		}
+	}
+}
+
+func (d *driver) collectGarbage() error {
+	result, err := d.db.Exec(d.gcQuery, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	if removed, err := result.RowsAffected(); err == nil {
+		d.events.Collected(int(removed))
+	}
+
+	return nil
+}
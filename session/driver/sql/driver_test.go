@@ -0,0 +1,333 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"github.com/censoredgit/light/session"
+	"github.com/censoredgit/light/session/sessiontest"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRow struct {
+	data    string
+	expire  int64
+	created int64
+	version int64
+}
+
+type fakeDB struct {
+	lock sync.Mutex
+	rows map[string]*fakeRow
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() sqldriver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (sqldriver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (sqldriver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		id := args[0].(string)
+		row := &fakeRow{
+			data:    args[1].(string),
+			expire:  args[2].(int64),
+			created: args[3].(int64),
+			version: args[4].(int64),
+		}
+		if exists, ok := s.db.rows[id]; ok {
+			if exists.version != row.version-1 {
+				return sqldriver.RowsAffected(0), nil
+			}
+			row.created = exists.created
+		}
+		s.db.rows[id] = row
+		return sqldriver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE") && strings.Contains(s.query, " < "):
+		var affected int64
+		for id, row := range s.db.rows {
+			if row.expire < args[0].(int64) {
+				delete(s.db.rows, id)
+				affected++
+			}
+		}
+		return sqldriver.RowsAffected(affected), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.db.rows, args[0].(string))
+		return sqldriver.RowsAffected(1), nil
+	}
+
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+func (s *fakeStmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+
+	rows := &fakeRows{}
+	if row, ok := s.db.rows[args[0].(string)]; ok {
+		rows.values = [][]sqldriver.Value{{row.data, row.expire, row.created, row.version}}
+	}
+
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]sqldriver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"data", "expire", "created", "version"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []sqldriver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func setupDriver(t *testing.T) (*driver, *fakeDB) {
+	db := &fakeDB{rows: make(map[string]*fakeRow)}
+
+	d := Setup(
+		sql.OpenDB(db),
+		Postgres,
+		DefaultSchema(),
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+			Level: slog.LevelError,
+		})),
+		time.Minute,
+		0,
+	).(*driver)
+
+	if err := d.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d, db
+}
+
+func TestDriver(t *testing.T) {
+	const sessId = "111"
+
+	d, _ := setupDriver(t)
+
+	data, err := d.Open(sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = d.Open(sessId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.IsNew() || data.Get("test") != "test data" {
+		t.Fatal("sess row corrupted")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = d.Destroy(sessId); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ = d.Open(sessId); !data.IsNew() {
+		t.Fatal("destroyed session should be new")
+	}
+}
+
+func TestDriverOptimisticConcurrency(t *testing.T) {
+	const sessId = "111"
+
+	d, _ := setupDriver(t)
+
+	data, _ := d.Open(sessId)
+	data.Set("test", "test data")
+	if err := d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := d.Open(sessId)
+	second, _ := d.Open(sessId)
+
+	first.Set("test", "first")
+	if err := d.Close(first); err != nil {
+		t.Fatal(err)
+	}
+
+	second.Set("test", "second")
+	if err := d.Close(second); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	if data, _ = d.Open(sessId); data.Get("test") != "first" {
+		t.Fatal("stale write should be rejected")
+	}
+}
+
+func TestDriverGarbageAndCorruption(t *testing.T) {
+	d, db := setupDriver(t)
+
+	db.rows["expired"] = &fakeRow{data: "{}", expire: time.Now().Add(-time.Minute).UnixMilli(), version: 1}
+	db.rows["alive"] = &fakeRow{data: "{}", expire: time.Now().Add(time.Minute).UnixMilli(), version: 1}
+	db.rows["corrupted"] = &fakeRow{data: "{", expire: time.Now().Add(time.Minute).UnixMilli(), version: 1}
+
+	if err := d.collectGarbage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.rows["expired"]; ok {
+		t.Fatal("expired session should be collected")
+	}
+	if _, ok := db.rows["alive"]; !ok {
+		t.Fatal("alive session should be kept")
+	}
+
+	data, err := d.Open("corrupted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.IsEmpty() {
+		t.Fatal("corrupted session should be empty")
+	}
+	data.Set("test", "test data")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDriverEncodings(t *testing.T) {
+	d, db := setupDriver(t)
+
+	data, err := d.Open("encodings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("test", "test data")
+	if err = session.SetAs(data, "items", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	expire := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	db.rows["encodings"].expire = expire.UnixMilli()
+
+	if data, err = d.Open("encodings"); err != nil {
+		t.Fatal(err)
+	}
+	if data.Get("test") != "test data" || data.Encoding("items") == "" {
+		t.Errorf("values and encodings should be restored, got %v %v", data.All(), data.Encodings())
+	}
+	if !data.Expire().Equal(expire) {
+		t.Errorf("expire should be taken from the column, got %v", data.Expire())
+	}
+}
+
+func TestDriverConformance(t *testing.T) {
+	sessiontest.Run(t, func(t *testing.T) *sessiontest.Harness {
+		db := &fakeDB{rows: make(map[string]*fakeRow)}
+		d := Setup(
+			sql.OpenDB(db),
+			Postgres,
+			DefaultSchema(),
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+			time.Minute,
+			0,
+		).(*driver)
+
+		return &sessiontest.Harness{
+			Driver: d,
+			CollectGarbage: func() {
+				if err := d.collectGarbage(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			Corrupt: func(id string) {
+				db.lock.Lock()
+				defer db.lock.Unlock()
+				db.rows[id].data = `{"data":`
+			},
+			Conflict: ErrConflict,
+		}
+	})
+}
+
+func TestDialects(t *testing.T) {
+	s := DefaultSchema()
+
+	if q := Postgres.upsert(s, Postgres.placeholders(1, 5)); !strings.Contains(q, "($1, $2, $3, $4, $5)") ||
+		!strings.Contains(q, "ON CONFLICT (id)") {
+		t.Errorf("unexpected postgres upsert: %s", q)
+	}
+	if q := MySQL.upsert(s, MySQL.placeholders(1, 5)); !strings.Contains(q, "(?, ?, ?, ?, ?)") ||
+		!strings.Contains(q, "ON DUPLICATE KEY UPDATE") {
+		t.Errorf("unexpected mysql upsert: %s", q)
+	}
+	if q := SQLite.upsert(s, SQLite.placeholders(1, 5)); !strings.Contains(q, "excluded.version - 1") {
+		t.Errorf("unexpected sqlite upsert: %s", q)
+	}
+
+	s.Table = "sessions; DROP TABLE users"
+	if err := s.validate(); err == nil {
+		t.Error("invalid identifier should be rejected")
+	}
+}
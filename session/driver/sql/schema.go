@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+type Schema struct {
+	Table         string
+	IdColumn      string
+	DataColumn    string
+	ExpireColumn  string
+	CreatedColumn string
+	VersionColumn string
+}
+
+func DefaultSchema() Schema {
+	return Schema{
+		Table:         "sessions",
+		IdColumn:      "id",
+		DataColumn:    "data",
+		ExpireColumn:  "expire",
+		CreatedColumn: "created",
+		VersionColumn: "version",
+	}
+}
+
+func (s Schema) validate() error {
+	for _, identifier := range []string{
+		s.Table, s.IdColumn, s.DataColumn, s.ExpireColumn, s.CreatedColumn, s.VersionColumn,
+	} {
+		if !identifierPattern.MatchString(identifier) {
+			return fmt.Errorf("invalid sql identifier %q", identifier)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,310 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+const (
+	encryptedPayloadKey = "_encrypted"
+	sealKeyInfo         = "session seal"
+	ownerKeyInfo        = "session owner digest"
+)
+
+var ErrNoEncryptionKeys = errors.New("at least one encryption key required")
+var ErrDecrypt = errors.New("session data can not be decrypted")
+
+type encryptedPayload struct {
+	Data      map[string]string `json:"data"`
+	Encodings map[string]string `json:"encodings,omitempty"`
+}
+
+// EncryptedDriver seals session values with AES-GCM before they reach the
+// wrapped driver. The first key encrypts, all keys decrypt. The owner is
+// stored as a keyed digest and the metadata is sealed into its user agent
+// field, so owner listing keeps working through the wrapped driver and
+// SessionsByOwner opens the metadata again. Sealing and owner digests use
+// separate keys derived from every key with HKDF.
+type EncryptedDriver struct {
+	driver    Driver
+	log       *slog.Logger
+	keys      [][]byte
+	aeads     []cipher.AEAD
+	ownerKeys [][]byte
+}
+
+func NewEncryptedDriver(driver Driver, log *slog.Logger, keys ...[]byte) *EncryptedDriver {
+	return &EncryptedDriver{
+		driver: driver,
+		log:    log.With(slog.String("session.driver", "encrypted")),
+		keys:   keys,
+	}
+}
+
+func (d *EncryptedDriver) Observe(events *DriverEvents) {
+	if observable, ok := d.driver.(ObservableDriver); ok {
+		observable.Observe(events)
+	}
+}
+
+func (d *EncryptedDriver) Init() error {
+	if len(d.keys) == 0 {
+		return ErrNoEncryptionKeys
+	}
+
+	d.aeads = make([]cipher.AEAD, 0, len(d.keys))
+	d.ownerKeys = make([][]byte, 0, len(d.keys))
+	for _, key := range d.keys {
+		sealKey, err := hkdf.Key(sha256.New, key, nil, sealKeyInfo, len(key))
+		if err != nil {
+			return fmt.Errorf("session encrypted driver init error: %w", err)
+		}
+
+		ownerKey, err := hkdf.Key(sha256.New, key, nil, ownerKeyInfo, sha256.Size)
+		if err != nil {
+			return fmt.Errorf("session encrypted driver init error: %w", err)
+		}
+
+		block, err := aes.NewCipher(sealKey)
+		if err != nil {
+			return fmt.Errorf("session encrypted driver init error: %w", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("session encrypted driver init error: %w", err)
+		}
+
+		d.aeads = append(d.aeads, aead)
+		d.ownerKeys = append(d.ownerKeys, ownerKey)
+	}
+
+	return d.driver.Init()
+}
+
+func (d *EncryptedDriver) Open(id string) (*Data, error) {
+	data, err := d.driver.Open(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.isNew || data.IsEmpty() {
+		return data, nil
+	}
+
+	sealed, ok := data.data[encryptedPayloadKey]
+	if !ok {
+		data.isModified = true
+		return data, nil
+	}
+
+	payload := encryptedPayload{}
+	plain, keyIndex, err := d.open(id, sealed)
+	if err == nil {
+		err = json.Unmarshal(plain, &payload)
+	}
+	if err != nil {
+		d.log.Warn(fmt.Sprintf("session %s data can not be decrypted: %s", id, err.Error()))
+		data.data = make(map[string]string)
+		data.encodings = nil
+		data.isModified = true
+		return data, nil
+	}
+
+	data.data = payload.Data
+	if data.data == nil {
+		data.data = make(map[string]string)
+	}
+	data.encodings = payload.Encodings
+	if keyIndex > 0 {
+		data.isModified = true
+	}
+
+	return data, nil
+}
+
+func (d *EncryptedDriver) Close(data *Data) error {
+	if !data.isNew && !data.isModified {
+		return d.driver.Close(data)
+	}
+
+	values, encodings := data.data, data.encodings
+
+	sealed, err := d.sealed(data.id, values, data.Encodings())
+	if err != nil {
+		_ = d.driver.Close(data)
+		return fmt.Errorf("session encrypted driver close error: %w", err)
+	}
+
+	data.data = sealed
+	data.encodings = nil
+
+	err = d.driver.Close(data)
+
+	data.data, data.encodings = values, encodings
+
+	return err
+}
+
+func (d *EncryptedDriver) Destroy(id string) error {
+	destroyer, ok := d.driver.(Destroyer)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return destroyer.Destroy(id)
+}
+
+func (d *EncryptedDriver) Touch(id string) error {
+	toucher, ok := d.driver.(Toucher)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return toucher.Touch(id)
+}
+
+func (d *EncryptedDriver) SessionsByOwner(owner string) ([]SessionInfo, error) {
+	ownerDriver, ok := d.driver.(OwnerDriver)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	infos := make([]SessionInfo, 0)
+	for _, key := range d.ownerKeys {
+		found, err := ownerDriver.SessionsByOwner(d.ownerDigest(key, owner))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range found {
+			if slices.ContainsFunc(infos, func(i SessionInfo) bool { return i.Id == info.Id }) {
+				continue
+			}
+			info.Owner = owner
+			info.Metadata = d.openMetadata(info.Id, info.Metadata)
+			infos = append(infos, info)
+		}
+	}
+
+	return infos, nil
+}
+
+func (d *EncryptedDriver) DestroyByOwner(owner string, keepIds ...string) error {
+	ownerDriver, ok := d.driver.(OwnerDriver)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	for _, key := range d.ownerKeys {
+		if err := ownerDriver.DestroyByOwner(d.ownerDigest(key, owner), keepIds...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sealed returns the values to store: the sealed payload, the owner digest
+// and the sealed metadata.
+func (d *EncryptedDriver) sealed(id string, values, encodings map[string]string) (map[string]string, error) {
+	plain, err := json.Marshal(encryptedPayload{Data: values, Encodings: encodings})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := d.seal(id, plain)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := map[string]string{encryptedPayloadKey: payload}
+
+	owner := values[ownerKey]
+	if owner == "" {
+		return sealed, nil
+	}
+
+	sealed[ownerKey] = d.ownerDigest(d.ownerKeys[0], owner)
+
+	if meta, ok := values[metadataKey]; ok {
+		sealedMeta, err := d.seal(id, []byte(meta))
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(Metadata{UserAgent: sealedMeta})
+		if err != nil {
+			return nil, err
+		}
+
+		sealed[metadataKey] = string(b)
+	}
+
+	return sealed, nil
+}
+
+func (d *EncryptedDriver) openMetadata(id string, stored Metadata) Metadata {
+	meta := Metadata{}
+
+	plain, _, err := d.open(id, stored.UserAgent)
+	if err == nil {
+		err = json.Unmarshal(plain, &meta)
+	}
+	if err != nil {
+		d.log.Warn(fmt.Sprintf("session %s metadata can not be decrypted: %s", id, err.Error()))
+	}
+
+	return meta
+}
+
+func (d *EncryptedDriver) seal(id string, plain []byte) (string, error) {
+	aead := d.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(id))), nil
+}
+
+func (d *EncryptedDriver) open(id string, sealed string) ([]byte, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, aead := range d.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(id))
+		if err != nil {
+			continue
+		}
+
+		return plain, i, nil
+	}
+
+	return nil, 0, ErrDecrypt
+}
+
+func (d *EncryptedDriver) ownerDigest(key []byte, owner string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(owner))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
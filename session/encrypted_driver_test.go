@@ -0,0 +1,117 @@
+package session
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncryptedDriver(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+
+	storage := &storageDriver{}
+	logs := bytes.Buffer{}
+
+	open := func(keys ...[]byte) *EncryptedDriver {
+		stored := storage.storage
+		d := NewEncryptedDriver(storage, slog.New(slog.NewTextHandler(&logs, nil)), keys...)
+		if err := d.Init(); err != nil {
+			t.Fatal(err)
+		}
+		if stored != nil {
+			storage.storage = stored
+		}
+		return d
+	}
+
+	stored := func(id string) string {
+		values := make([]string, 0)
+		for k, v := range storage.storage[id].All() {
+			values = append(values, k+"="+v)
+		}
+		return strings.Join(values, ";")
+	}
+
+	d := open(oldKey)
+
+	data, err := d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("secret", "auth-id-42")
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Get("secret") != "auth-id-42" {
+		t.Error("closed data should keep plain values")
+	}
+	if raw := stored("1"); strings.Contains(raw, "auth-id-42") || !strings.Contains(raw, encryptedPayloadKey) {
+		t.Fatalf("stored data should be encrypted, got %s", raw)
+	}
+
+	d = open(newKey, oldKey)
+	data, err = d.Open("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Get("secret") != "auth-id-42" || !data.IsModified() {
+		t.Fatal("data encrypted with old key should be readable and re-encrypted")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+
+	d = open(newKey)
+	if data, err = d.Open("1"); err != nil || data.Get("secret") != "auth-id-42" {
+		t.Fatal("data should be re-encrypted with new key")
+	}
+	_ = d.Close(data)
+
+	storage.storage["2"] = RestoreData("2", map[string]string{"legacy": "plain"}, time.Now().Add(time.Minute), time.Now())
+	data, err = d.Open("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Get("legacy") != "plain" || !data.IsModified() {
+		t.Fatal("plaintext session should be readable and marked for migration")
+	}
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+	if raw := stored("2"); strings.Contains(raw, "plain") {
+		t.Errorf("plaintext session should be migrated, got %s", raw)
+	}
+
+	storage.storage["3"] = RestoreData("3", map[string]string{encryptedPayloadKey: storage.storage["1"].Get(encryptedPayloadKey)}, time.Now().Add(time.Minute), time.Now())
+	if data, err = d.Open("3"); err != nil || !data.IsEmpty() {
+		t.Error("payload bound to another session should be rejected")
+	}
+
+	data, err = d.Open("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.SetOwner("john", Metadata{IP: "192.0.2.1", UserAgent: "test-agent"})
+	if err = d.Close(data); err != nil {
+		t.Fatal(err)
+	}
+	if raw := stored("4"); strings.Contains(raw, "john") || strings.Contains(raw, "192.0.2.1") || strings.Contains(raw, "test-agent") {
+		t.Errorf("owner and metadata should be stored encrypted, got %s", raw)
+	}
+	if owner := storage.storage["4"].Get(ownerKey); owner == "" || owner == d.ownerDigest(newKey, "john") {
+		t.Error("owner digest should not use the encryption key")
+	}
+
+	logs.Reset()
+	d = open([]byte("0123456789abcdef0123456789abcdef"))
+	if data, err = d.Open("1"); err != nil || !data.IsEmpty() {
+		t.Fatal("data encrypted with unknown key should be dropped")
+	}
+	if !strings.Contains(logs.String(), "session 1 data can not be decrypted") {
+		t.Errorf("decryption failure should be logged, got %q", logs.String())
+	}
+}
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+const (
+	flashNewKey = "_flash.new"
+	flashOldKey = "_flash.old"
+)
+
+func (d *Data) Flash(key, value string) {
+	d.Set(key, value)
+	d.markFlash(key)
+}
+
+func FlashAs[T any](d *Data, key string, value T) error {
+	if err := SetAs(d, key, value); err != nil {
+		return err
+	}
+
+	d.markFlash(key)
+
+	return nil
+}
+
+func (d *Data) Now(key, value string) {
+	d.Set(key, value)
+
+	d.setFlashKeys(flashNewKey, slices.DeleteFunc(d.flashKeys(flashNewKey), func(k string) bool { return k == key }))
+	d.setFlashKeys(flashOldKey, appendFlashKey(d.flashKeys(flashOldKey), key))
+}
+
+func (d *Data) Reflash() {
+	keys := d.flashKeys(flashNewKey)
+	for _, key := range d.flashKeys(flashOldKey) {
+		keys = appendFlashKey(keys, key)
+	}
+
+	d.setFlashKeys(flashNewKey, keys)
+	d.setFlashKeys(flashOldKey, nil)
+}
+
+func (d *Data) Keep(keys ...string) {
+	old := d.flashKeys(flashOldKey)
+	kept := d.flashKeys(flashNewKey)
+
+	for _, key := range keys {
+		if i := slices.Index(old, key); i >= 0 {
+			old = slices.Delete(old, i, i+1)
+			kept = appendFlashKey(kept, key)
+		}
+	}
+
+	d.setFlashKeys(flashNewKey, kept)
+	d.setFlashKeys(flashOldKey, old)
+}
+
+func (d *Data) IsFlash(key string) bool {
+	return slices.Contains(d.flashKeys(flashNewKey), key) || slices.Contains(d.flashKeys(flashOldKey), key)
+}
+
+func (d *Data) ageFlash() {
+	if !d.Has(flashNewKey) && !d.Has(flashOldKey) {
+		return
+	}
+
+	fresh := d.flashKeys(flashNewKey)
+	for _, key := range d.flashKeys(flashOldKey) {
+		if !slices.Contains(fresh, key) {
+			d.Delete(key)
+		}
+	}
+
+	d.setFlashKeys(flashOldKey, fresh)
+	d.setFlashKeys(flashNewKey, nil)
+}
+
+func (d *Data) markFlash(key string) {
+	d.setFlashKeys(flashNewKey, appendFlashKey(d.flashKeys(flashNewKey), key))
+	d.setFlashKeys(flashOldKey, slices.DeleteFunc(d.flashKeys(flashOldKey), func(k string) bool { return k == key }))
+}
+
+func (d *Data) flashKeys(name string) []string {
+	var keys []string
+	if value := d.Get(name); value != "" {
+		_ = json.Unmarshal([]byte(value), &keys)
+	}
+
+	return keys
+}
+
+func (d *Data) setFlashKeys(name string, keys []string) {
+	if len(keys) == 0 {
+		if d.Has(name) {
+			d.Delete(name)
+		}
+		return
+	}
+
+	b, _ := json.Marshal(keys)
+	if d.Get(name) != string(b) {
+		d.Set(name, string(b))
+	}
+}
+
+func appendFlashKey(keys []string, key string) []string {
+	if slices.Contains(keys, key) {
+		return keys
+	}
+
+	return append(keys, key)
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"github.com/censoredgit/light/session/hasher"
+	"testing"
+	"time"
+)
+
+func TestDataFlash(t *testing.T) {
+	m := MustSetup(&Config{
+		Hasher:     hasher.Md5Hasher{},
+		Salt:       "test",
+		TTL:        time.Minute,
+		CookieName: "test",
+		Driver:     &storageDriver{},
+	})
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := d.Id()
+
+	request := func(fn func(d *Data)) {
+		d, err := m.Init(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn(d)
+		if err = m.Close(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d.Flash("status", "saved")
+	d.Flash("kept", "yes")
+	d.Now("now", "only this request")
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	request(func(d *Data) {
+		if d.Has("now") {
+			t.Error("now value should not survive request")
+		}
+		if d.Get("status") != "saved" || d.Get("kept") != "yes" {
+			t.Error("flash values should be available on next request")
+		}
+		d.Keep("kept")
+	})
+
+	request(func(d *Data) {
+		if d.Has("status") {
+			t.Error("flash value should expire after one request")
+		}
+		if d.Get("kept") != "yes" {
+			t.Error("kept flash value should be available")
+		}
+		d.Reflash()
+	})
+
+	request(func(d *Data) {
+		if d.Get("kept") != "yes" {
+			t.Error("reflashed value should be available")
+		}
+	})
+
+	request(func(d *Data) {
+		if !d.IsEmpty() {
+			t.Errorf("all flash data should expire, got %v", d.All())
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package hasher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+type HmacSha256Hasher struct {
+	key []byte
+}
+
+func NewHmacSha256Hasher(key []byte) HmacSha256Hasher {
+	return HmacSha256Hasher{key: key}
+}
+
+func (m HmacSha256Hasher) Sum(b []byte) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write(b)
+
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+func (m HmacSha256Hasher) BlockSize() int {
+	return sha256.BlockSize
+}
@@ -0,0 +1,17 @@
+package hasher
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+type Sha256Hasher struct {
+}
+
+func (m Sha256Hasher) Sum(b []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+func (m Sha256Hasher) BlockSize() int {
+	return sha256.BlockSize
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultIdBytes = 32
+	MinIdBytes     = 16
+	MinSigningKey  = 32
+
+	maxIdLength          = 256
+	idSignatureSeparator = "."
+)
+
+type IdGenerator interface {
+	Generate() (string, error)
+}
+
+type SecureIdGenerator struct {
+	bytes int
+}
+
+func NewSecureIdGenerator(bytes int) *SecureIdGenerator {
+	if bytes == 0 {
+		bytes = DefaultIdBytes
+	}
+
+	return &SecureIdGenerator{bytes: max(bytes, MinIdBytes)}
+}
+
+func (g *SecureIdGenerator) Generate() (string, error) {
+	b := make([]byte, g.bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type hashIdGenerator struct {
+	hasher Hasher
+	salt   string
+	pool   sync.Pool
+}
+
+func newHashIdGenerator(hasher Hasher, salt string) *hashIdGenerator {
+	return &hashIdGenerator{
+		hasher: hasher,
+		salt:   salt,
+		pool: sync.Pool{New: func() any {
+			return newIdBuffer(len(salt) + hasher.BlockSize())
+		}},
+	}
+}
+
+func (g *hashIdGenerator) Generate() (string, error) {
+	buf := g.pool.Get().(*idBuffer)
+	defer g.pool.Put(buf)
+	buf.Rewind()
+
+	_ = buf.ReadString(g.salt)
+
+	_, err := buf.Read(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	return g.hasher.Sum(buf.Bytes()), nil
+}
+
+func validId(id string) bool {
+	if id == "" || len(id) > maxIdLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+type idSigner struct {
+	keys [][]byte
+}
+
+func (s *idSigner) sign(id string) string {
+	return id + idSignatureSeparator + s.signature(s.keys[0], id)
+}
+
+func (s *idSigner) verify(value string) (string, bool) {
+	id, signature, ok := strings.Cut(value, idSignatureSeparator)
+	if !ok {
+		return "", false
+	}
+
+	for _, key := range s.keys {
+		if hmac.Equal([]byte(signature), []byte(s.signature(key, id))) {
+			return id, true
+		}
+	}
+
+	return "", false
+}
+
+func (s *idSigner) signature(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
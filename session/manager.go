@@ -1,15 +1,15 @@
 package session
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"sync"
 	"time"
 )
 
+const defaultCookiePath = "/"
+
 var ErrNotExists = errors.New("not exists")
 
 type Driver interface {
@@ -18,24 +18,42 @@ type Driver interface {
 	Close(data *Data) error
 }
 
+type Destroyer interface {
+	Destroy(id string) error
+}
+
+type CookieDriver interface {
+	OpenByRequest(id string, req *http.Request) (*Data, error)
+	Cookies(data *Data, template *http.Cookie) ([]*http.Cookie, error)
+}
+
 type Hasher interface {
 	Sum(b []byte) string
 	BlockSize() int
 }
 
 type Manager struct {
-	hasher              Hasher
-	driver              Driver
-	logger              *slog.Logger
-	salt                string
-	saltLength          int
-	ttl                 time.Duration
-	cookieName          string
-	sessionIdBufferPool sync.Pool
+	idGenerator       IdGenerator
+	signer            *idSigner
+	driver            Driver
+	codec             Codec
+	logger            *slog.Logger
+	ttl               time.Duration
+	maxLifetime       time.Duration
+	sliding           bool
+	cookieName        string
+	cookiePath        string
+	cookieDomain      string
+	cookieSecure      bool
+	cookieHttpOnly    bool
+	cookieSameSite    http.SameSite
+	cookiePartitioned bool
+	hooks             Hooks
+	stats             stats
 }
 
 func MustSetup(cfg *Config) *Manager {
-	if cfg.Hasher == nil {
+	if cfg.IdGenerator == nil && cfg.Hasher == nil {
 		panic("Hasher must be provided")
 	}
 	if cfg.Driver == nil {
@@ -44,49 +62,127 @@ func MustSetup(cfg *Config) *Manager {
 	if cfg.CookieName == "" {
 		panic("Cookie name must not be empty")
 	}
-	if cfg.Salt == "" {
+	if cfg.IdGenerator == nil && cfg.Salt == "" {
 		panic("Salt must not be empty")
 	}
 	if cfg.TTL == 0 {
 		panic("TTL must not be zero")
 	}
+	if cfg.MaxLifetime != 0 && cfg.MaxLifetime < cfg.TTL {
+		panic("MaxLifetime must not be less than TTL")
+	}
+	if cfg.CookieSameSite == http.SameSiteNoneMode && !cfg.CookieSecure {
+		panic("SameSite=None cookie must be secure")
+	}
+	if cfg.CookiePartitioned && !cfg.CookieSecure {
+		panic("Partitioned cookie must be secure")
+	}
+	for _, key := range cfg.SigningKeys {
+		if len(key) < MinSigningKey {
+			panic(fmt.Sprintf("Signing key must be at least %d bytes", MinSigningKey))
+		}
+	}
 
-	if err := cfg.Driver.Init(); err != nil {
-		panic(err.Error())
+	cookiePath := cfg.CookiePath
+	if cookiePath == "" {
+		cookiePath = defaultCookiePath
+	}
+
+	cookieSameSite := cfg.CookieSameSite
+	if cookieSameSite == 0 {
+		cookieSameSite = http.SameSiteLaxMode
+	}
+
+	codec := cfg.Codec
+	if codec == nil {
+		codec = JSONCodec
 	}
 
-	saltLength := len(cfg.Salt)
+	idGenerator := cfg.IdGenerator
+	if idGenerator == nil {
+		idGenerator = newHashIdGenerator(cfg.Hasher, cfg.Salt)
+	}
+
+	var signer *idSigner
+	if len(cfg.SigningKeys) > 0 {
+		signer = &idSigner{keys: cfg.SigningKeys}
+	}
+
+	m := &Manager{
+		idGenerator:       idGenerator,
+		signer:            signer,
+		driver:            cfg.Driver,
+		codec:             codec,
+		logger:            cfg.Logger,
+		ttl:               cfg.TTL,
+		maxLifetime:       cfg.MaxLifetime,
+		sliding:           cfg.Sliding,
+		cookieName:        cfg.CookieName,
+		cookiePath:        cookiePath,
+		cookieDomain:      cfg.CookieDomain,
+		cookieSecure:      cfg.CookieSecure,
+		cookieHttpOnly:    cfg.CookieHttpOnly,
+		cookieSameSite:    cookieSameSite,
+		cookiePartitioned: cfg.CookiePartitioned,
+		hooks:             cfg.Hooks,
+	}
+
+	if observable, ok := cfg.Driver.(ObservableDriver); ok {
+		observable.Observe(&DriverEvents{manager: m})
+	}
 
-	return &Manager{
-		hasher:     cfg.Hasher,
-		driver:     cfg.Driver,
-		logger:     cfg.Logger,
-		salt:       cfg.Salt,
-		saltLength: saltLength,
-		ttl:        cfg.TTL,
-		cookieName: cfg.CookieName,
-		sessionIdBufferPool: sync.Pool{New: func() any {
-			return newIdBuffer(saltLength + cfg.Hasher.BlockSize())
-		}},
+	if err := cfg.Driver.Init(); err != nil {
+		panic(err.Error())
 	}
+
+	return m
 }
 
 func (m *Manager) InitByRequest(req *http.Request) (*Data, error) {
+	id, _ := m.requestId(req)
+
+	return m.init(id, req)
+}
+
+// HasSession reports whether the request carries a valid session cookie.
+func (m *Manager) HasSession(req *http.Request) bool {
+	_, ok := m.requestId(req)
+
+	return ok
+}
+
+func (m *Manager) requestId(req *http.Request) (string, bool) {
 	c, err := req.Cookie(m.cookieName)
 	if err != nil {
-		return m.Init("")
+		return "", false
 	}
 	err = c.Valid()
 	if err != nil {
-		return m.Init("")
+		return "", false
 	}
 
-	return m.Init(c.Value)
+	id := c.Value
+	if m.signer != nil {
+		var ok bool
+		if id, ok = m.signer.verify(c.Value); !ok {
+			return "", false
+		}
+	}
+
+	return id, validId(id)
 }
 
 func (m *Manager) Init(id string) (*Data, error) {
+	return m.init(id, nil)
+}
+
+func (m *Manager) init(id string, req *http.Request) (*Data, error) {
 	var err error
 
+	if !validId(id) {
+		id = ""
+	}
+
 	if id == "" {
 		id, err = m.generateId()
 		if err != nil {
@@ -94,34 +190,224 @@ func (m *Manager) Init(id string) (*Data, error) {
 		}
 	}
 
+	start := time.Now()
+
+	data, err := m.open(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	m.opened(start)
+
+	data.codec = m.codec
+
+	if data.isNew {
+		m.created(data.id)
+		return data, nil
+	}
+
+	currentTime := time.Now()
+
+	if data.isExpired() || m.isLifetimeExceeded(data, currentTime) {
+		m.expired(data.id)
+		if err = m.restart(data, currentTime); err != nil {
+			_ = m.driver.Close(data)
+			return nil, fmt.Errorf("init error: %w", err)
+		}
+
+		return data, nil
+	}
+
+	if m.sliding {
+		m.slide(data, currentTime)
+	}
+
+	m.trackActivity(data, currentTime)
+
+	// flashes age on open, a cookie session is written before Close
+	data.ageFlash()
+
+	m.loaded(data.id)
+
+	return data, nil
+}
+
+func (m *Manager) open(id string, req *http.Request) (*Data, error) {
+	if cookieDriver, ok := m.driver.(CookieDriver); ok && req != nil {
+		return cookieDriver.OpenByRequest(id, req)
+	}
+
 	return m.driver.Open(id)
 }
 
 func (m *Manager) Close(data *Data) error {
-	return m.driver.Close(data)
+	if data.isInvalidated {
+		if err := m.destroy(data); err != nil {
+			return err
+		}
+
+		m.destroyed(data.id)
+
+		return nil
+	}
+
+	isDirty := data.isNew || data.isModified
+
+	if err := m.driver.Close(data); err != nil {
+		return err
+	}
+
+	if isDirty {
+		m.saved(data.id)
+	}
+
+	return nil
+}
+
+func (m *Manager) Regenerate(data *Data) error {
+	id, err := m.generateId()
+	if err != nil {
+		return fmt.Errorf("regenerate error: %w", err)
+	}
+
+	if _, err = m.driver.Open(id); err != nil {
+		return fmt.Errorf("regenerate error: %w", err)
+	}
+
+	oldId := data.id
+	values := data.data
+	expireTime := data.expireTime
+	data.data = make(map[string]string)
+
+	err = m.destroy(data)
+
+	data.id = id
+	data.data = values
+	data.expireTime = expireTime
+	data.isModified = true
+	data.renewCookie = true
+
+	if err != nil {
+		return fmt.Errorf("regenerate error: %w", err)
+	}
+
+	m.regenerated(oldId, id)
+
+	return nil
+}
+
+func (m *Manager) NeedsCookie(data *Data) bool {
+	return data.isNew || data.renewCookie || data.isInvalidated
+}
+
+func (m *Manager) Cookies(data *Data) ([]*http.Cookie, error) {
+	var err error
+	cookies := make([]*http.Cookie, 0, 2)
+
+	if cookieDriver, ok := m.driver.(CookieDriver); ok {
+		var driverCookies []*http.Cookie
+		driverCookies, err = cookieDriver.Cookies(data, m.cookie(data))
+		if err != nil {
+			err = fmt.Errorf("cookies error: %w", err)
+		}
+		cookies = append(cookies, driverCookies...)
+	}
+
+	if m.NeedsCookie(data) {
+		cookies = append(cookies, m.ToCookie(data))
+	}
+
+	return cookies, err
 }
 
 func (m *Manager) ToCookie(data *Data) *http.Cookie {
 	data.isNew = false
-	return &http.Cookie{
-		Name:    m.cookieName,
-		Value:   data.Id(),
-		Path:    "/",
-		Expires: data.Expire(),
+	data.renewCookie = false
+
+	return m.cookie(data)
+}
+
+func (m *Manager) cookie(data *Data) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:        m.cookieName,
+		Value:       m.cookieValue(data.Id()),
+		Path:        m.cookiePath,
+		Domain:      m.cookieDomain,
+		Expires:     data.Expire(),
+		Secure:      m.cookieSecure,
+		HttpOnly:    m.cookieHttpOnly,
+		SameSite:    m.cookieSameSite,
+		Partitioned: m.cookiePartitioned,
+	}
+
+	if data.isInvalidated {
+		cookie.Value = ""
+		cookie.Expires = time.Time{}
+		cookie.MaxAge = -1
+	}
+
+	return cookie
+}
+
+func (m *Manager) cookieValue(id string) string {
+	if m.signer == nil {
+		return id
 	}
+
+	return m.signer.sign(id)
 }
 
-func (m *Manager) generateId() (string, error) {
-	buf := m.sessionIdBufferPool.Get().(*idBuffer)
-	defer m.sessionIdBufferPool.Put(buf)
-	buf.Rewind()
+func (m *Manager) isLifetimeExceeded(data *Data, currentTime time.Time) bool {
+	return m.maxLifetime > 0 && data.createdTime.Add(m.maxLifetime).Before(currentTime)
+}
 
-	_ = buf.ReadString(m.salt)
+func (m *Manager) slide(data *Data, currentTime time.Time) {
+	// sliding on every request would rewrite the session and its cookie
+	// each time, so it waits until half of the ttl is used
+	if data.expireTime.Sub(currentTime) > m.ttl/2 {
+		return
+	}
 
-	_, err := buf.Read(rand.Reader)
-	if err != nil {
-		return "", err
+	expireTime := currentTime.Add(m.ttl)
+	if deadline := data.createdTime.Add(m.maxLifetime); m.maxLifetime > 0 && deadline.Before(expireTime) {
+		expireTime = deadline
 	}
 
-	return m.hasher.Sum(buf.Bytes()), nil
+	if !expireTime.After(data.expireTime) {
+		return
+	}
+
+	data.expireTime = expireTime
+	data.isModified = true
+	data.renewCookie = true
+}
+
+func (m *Manager) restart(data *Data, currentTime time.Time) error {
+	data.Empty()
+	data.createdTime = currentTime
+	data.expireTime = currentTime.Add(m.ttl)
+
+	return m.Regenerate(data)
+}
+
+func (m *Manager) destroy(data *Data) error {
+	clear(data.data)
+	data.expireTime = time.Now()
+	data.isModified = true
+
+	if err := m.driver.Close(data); err != nil {
+		return err
+	}
+
+	if destroyer, ok := m.driver.(Destroyer); ok {
+		if err := destroyer.Destroy(data.id); !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) generateId() (string, error) {
+	return m.idGenerator.Generate()
 }
@@ -1,10 +1,12 @@
 package session
 
 import (
+	"errors"
 	"github.com/censoredgit/light/session/hasher"
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"testing"
 	"time"
 )
@@ -56,6 +58,8 @@ func TestManager(t *testing.T) {
 			t.Fatal("data should be modified")
 		}
 
+		nData.OnSaved()
+
 		if nData.IsModified() {
 			t.Fatal("data should be not modified")
 		}
@@ -76,10 +80,215 @@ func TestManager(t *testing.T) {
 	}
 }
 
-type dummyDriver struct {
+func TestManagerRegenerate(t *testing.T) {
+	m := makeManager()
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ToCookie(d)
+
+	oldId := d.Id()
+	d.Set("test", "test1")
+
+	if m.NeedsCookie(d) {
+		t.Fatal("cookie already sent")
+	}
+
+	if err = m.Regenerate(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Id() == oldId {
+		t.Fatal("id should be changed")
+	}
+
+	if d.Get("test") != "test1" {
+		t.Fatal("data should be moved to the new id")
+	}
+
+	if !m.NeedsCookie(d) {
+		t.Fatal("cookie should be re-issued")
+	}
+
+	if m.ToCookie(d).Value != d.Id() {
+		t.Fatal("cookie value not eq data.id")
+	}
+
+	if !slices.Contains(m.driver.(*dummyDriver).destroyed, oldId) {
+		t.Fatal("old id should be destroyed")
+	}
+}
+
+func TestManagerInvalidate(t *testing.T) {
+	m := makeManager()
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ToCookie(d)
+
+	d.Set("test", "test1")
+	d.Invalidate()
+
+	if !d.IsEmpty() {
+		t.Fatal("data should be empty")
+	}
+
+	if !m.NeedsCookie(d) {
+		t.Fatal("cookie should be expired")
+	}
+
+	if cookie := m.ToCookie(d); cookie.MaxAge >= 0 {
+		t.Fatal("cookie should be expired")
+	}
+
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Contains(m.driver.(*dummyDriver).destroyed, d.Id()) {
+		t.Fatal("id should be destroyed")
+	}
+}
+
+func TestManagerCookiePolicy(t *testing.T) {
+	m := MustSetup(&Config{
+		Hasher:            hasher.Md5Hasher{},
+		Salt:              "test",
+		TTL:               time.Minute,
+		CookieName:        "test",
+		CookieDomain:      "example.com",
+		CookieSecure:      true,
+		CookieHttpOnly:    true,
+		CookiePartitioned: true,
+		Driver:            &dummyDriver{},
+	})
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookie := m.ToCookie(d)
+	if cookie.Path != "/" || cookie.Domain != "example.com" || !cookie.Secure || !cookie.HttpOnly ||
+		!cookie.Partitioned || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected cookie attributes: %v", cookie)
+	}
+}
+
+func TestManagerSliding(t *testing.T) {
+	driver := &storageDriver{}
+	m := MustSetup(&Config{
+		Hasher:      hasher.Md5Hasher{},
+		Salt:        "test",
+		TTL:         time.Minute,
+		CookieName:  "test",
+		Driver:      driver,
+		Sliding:     true,
+		MaxLifetime: time.Hour,
+	})
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ToCookie(d)
+	d.Set("test", "test1")
+	id := d.Id()
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	driver.storage[id].expireTime = time.Now().Add(time.Second)
+
+	d, err = m.Init(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Expire().Before(time.Now().Add(time.Second * 30)) {
+		t.Fatal("expire time should be extended")
+	}
+	if !m.NeedsCookie(d) {
+		t.Fatal("cookie should be re-issued")
+	}
+	m.ToCookie(d)
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = m.Init(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.IsModified() || m.NeedsCookie(d) {
+		t.Fatal("recently extended session should not slide again")
+	}
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	currentTime := time.Now()
+	driver.storage[id].createdTime = currentTime.Add(-time.Hour + time.Second)
+	driver.storage[id].expireTime = currentTime.Add(time.Second / 2)
+
+	d, err = m.Init(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Expire().After(d.Created().Add(time.Hour)) {
+		t.Fatal("expire time should not exceed max lifetime")
+	}
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	driver.storage[id].createdTime = time.Now().Add(-time.Hour * 2)
+
+	d, err = m.Init(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Id() == id || d.Has("test") {
+		t.Fatal("session over max lifetime should be restarted")
+	}
+}
+
+type storageDriver struct {
 	storage map[string]*Data
 }
 
+func (d *storageDriver) Init() error {
+	d.storage = make(map[string]*Data)
+	return nil
+}
+
+func (d *storageDriver) Open(id string) (*Data, error) {
+	if data, ok := d.storage[id]; ok {
+		return RestoreData(id, data.All(), data.Expire(), data.Created()), nil
+	}
+	return NewData(id, time.Minute), nil
+}
+
+func (d *storageDriver) Close(data *Data) error {
+	if data.IsNew() || data.IsModified() {
+		d.storage[data.Id()] = RestoreData(data.Id(), data.All(), data.Expire(), data.Created())
+	}
+	return nil
+}
+
+func (d *storageDriver) Destroy(id string) error {
+	delete(d.storage, id)
+	return nil
+}
+
+type dummyDriver struct {
+	storage   map[string]*Data
+	destroyed []string
+}
+
 func (d *dummyDriver) Init() error {
 	d.storage = make(map[string]*Data)
 	return nil
@@ -99,3 +308,209 @@ func (d *dummyDriver) Close(data *Data) error {
 
 	return nil
 }
+
+func (d *dummyDriver) Destroy(id string) error {
+	delete(d.storage, id)
+	d.destroyed = append(d.destroyed, id)
+
+	return nil
+}
+
+func TestManagerOwnerSessions(t *testing.T) {
+	driver := &storageDriver{}
+	m := MustSetup(&Config{
+		Hasher:     hasher.Md5Hasher{},
+		Salt:       "test",
+		TTL:        time.Minute,
+		CookieName: "test",
+		Driver:     driver,
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test agent")
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetOwner("john", NewMetadata(req))
+	id := d.Id()
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := driver.storage[id].Metadata()
+	if meta.IP != "10.0.0.1" || meta.UserAgent != "test agent" {
+		t.Fatalf("metadata should be stored, got %+v", meta)
+	}
+
+	meta.LastActivity = time.Now().Add(-time.Hour)
+	driver.storage[id].setMetadata(meta)
+
+	d, err = m.Init(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Owner() != "john" || time.Since(d.Metadata().LastActivity) > time.Second {
+		t.Error("last activity should be updated")
+	}
+
+	if _, err = m.SessionsFor("john"); !errors.Is(err, ErrNotSupported) {
+		t.Error("driver without owner support should return ErrNotSupported")
+	}
+}
+
+func TestManagerSecureId(t *testing.T) {
+	m := MustSetup(&Config{
+		TTL:         time.Minute,
+		CookieName:  "test",
+		Driver:      &storageDriver{},
+		IdGenerator: NewSecureIdGenerator(0),
+	})
+
+	ids := make(map[string]struct{})
+	for range 1000 {
+		d, err := m.Init("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Id()) != 43 || !validId(d.Id()) {
+			t.Fatalf("unexpected id format %q", d.Id())
+		}
+		if _, ok := ids[d.Id()]; ok {
+			t.Fatal("duplicate id")
+		}
+		ids[d.Id()] = struct{}{}
+	}
+
+	d, err := m.Init("../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.IsNew() || d.Id() == "../../etc/passwd" {
+		t.Error("malformed id should be replaced")
+	}
+}
+
+func TestManagerSignedCookie(t *testing.T) {
+	oldKey := []byte("0123456789abcdef0123456789abcdef")
+	newKey := []byte("fedcba9876543210fedcba9876543210")
+
+	setup := func(keys ...[]byte) *Manager {
+		return MustSetup(&Config{
+			TTL:         time.Minute,
+			CookieName:  "test",
+			Driver:      &dummyDriver{},
+			IdGenerator: NewSecureIdGenerator(0),
+			SigningKeys: keys,
+		})
+	}
+
+	request := func(m *Manager, value string) *Data {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "test", Value: value})
+		d, err := m.InitByRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	m := setup(oldKey)
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := m.ToCookie(d)
+	if cookie.Value == d.Id() {
+		t.Fatal("cookie value should be signed")
+	}
+
+	if request(m, cookie.Value).Id() != d.Id() {
+		t.Error("signed cookie should be accepted")
+	}
+
+	if request(m, d.Id()).Id() == d.Id() {
+		t.Error("unsigned cookie should be rejected")
+	}
+
+	if request(m, d.Id()+".forged").Id() == d.Id() {
+		t.Error("forged signature should be rejected")
+	}
+
+	if request(setup(newKey, oldKey), cookie.Value).Id() != d.Id() {
+		t.Error("cookie signed with rotated key should be accepted")
+	}
+
+	if request(setup(newKey), cookie.Value).Id() == d.Id() {
+		t.Error("cookie signed with unknown key should be rejected")
+	}
+}
+
+func TestManagerHooks(t *testing.T) {
+	events := make([]string, 0)
+	driver := &storageDriver{}
+	m := MustSetup(&Config{
+		TTL:         time.Minute,
+		CookieName:  "test",
+		Driver:      driver,
+		IdGenerator: NewSecureIdGenerator(0),
+		Hooks: Hooks{
+			OnCreated:     func(id string) { events = append(events, "created") },
+			OnLoaded:      func(id string) { events = append(events, "loaded") },
+			OnSaved:       func(id string) { events = append(events, "saved") },
+			OnRegenerated: func(oldId, newId string) { events = append(events, "regenerated") },
+			OnExpired:     func(id string) { events = append(events, "expired") },
+			OnDestroyed:   func(id string) { events = append(events, "destroyed") },
+		},
+	})
+
+	d, err := m.Init("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Set("test", "test1")
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err = m.Init(d.Id()); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, err = m.Init(d.Id()); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Regenerate(d); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	driver.storage[d.Id()].expireTime = time.Now().Add(-time.Second)
+	if d, err = m.Init(d.Id()); err != nil {
+		t.Fatal(err)
+	}
+	d.Invalidate()
+	if err = m.Close(d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"created", "saved", "loaded", "loaded", "regenerated", "saved", "expired", "regenerated", "destroyed"}
+	if !slices.Equal(events, expected) {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	stats := m.Stats()
+	if stats.Created != 1 || stats.Loaded != 2 || stats.Saved != 2 || stats.Regenerated != 2 || stats.Expired != 1 || stats.Destroyed != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.Opens != 4 || stats.OpenTime <= 0 || stats.MaxOpenTime > stats.OpenTime || stats.AvgOpenTime() <= 0 {
+		t.Errorf("unexpected open timings %+v", stats)
+	}
+}
@@ -0,0 +1,158 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	ownerKey             = "_owner"
+	metadataKey          = "_meta"
+	lastActivityInterval = time.Minute
+)
+
+var ErrNotSupported = errors.New("not supported")
+
+type Toucher interface {
+	Touch(id string) error
+}
+
+type OwnerDriver interface {
+	SessionsByOwner(owner string) ([]SessionInfo, error)
+	DestroyByOwner(owner string, keepIds ...string) error
+}
+
+type Metadata struct {
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+type SessionInfo struct {
+	Id       string
+	Owner    string
+	Metadata Metadata
+	Created  time.Time
+	Expire   time.Time
+}
+
+func NewMetadata(req *http.Request) Metadata {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	return Metadata{
+		IP:           ip,
+		UserAgent:    req.UserAgent(),
+		LastActivity: time.Now(),
+	}
+}
+
+func (d *Data) Owner() string {
+	return d.Get(ownerKey)
+}
+
+func (d *Data) SetOwner(owner string, meta Metadata) {
+	d.Set(ownerKey, owner)
+	d.setMetadata(meta)
+}
+
+func (d *Data) Metadata() Metadata {
+	meta := Metadata{}
+	if value := d.Get(metadataKey); value != "" {
+		_ = json.Unmarshal([]byte(value), &meta)
+	}
+
+	return meta
+}
+
+func (d *Data) Info() SessionInfo {
+	return SessionInfo{
+		Id:       d.id,
+		Owner:    d.Owner(),
+		Metadata: d.Metadata(),
+		Created:  d.createdTime,
+		Expire:   d.expireTime,
+	}
+}
+
+func (d *Data) setMetadata(meta Metadata) {
+	b, _ := json.Marshal(meta)
+	d.Set(metadataKey, string(b))
+	d.setEncoding(metadataKey, JSONCodecName)
+}
+
+func (m *Manager) Touch(id string) error {
+	toucher, ok := m.driver.(Toucher)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return toucher.Touch(id)
+}
+
+func (m *Manager) Destroy(id string) error {
+	destroyer, ok := m.driver.(Destroyer)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	if err := destroyer.Destroy(id); err != nil {
+		return err
+	}
+
+	m.destroyed(id)
+
+	return nil
+}
+
+func (m *Manager) SessionsFor(authId string) ([]SessionInfo, error) {
+	ownerDriver, ok := m.driver.(OwnerDriver)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return ownerDriver.SessionsByOwner(authId)
+}
+
+func (m *Manager) DestroyOthers(authId, keepId string) error {
+	ownerDriver, ok := m.driver.(OwnerDriver)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	infos, err := ownerDriver.SessionsByOwner(authId)
+	if err != nil {
+		return err
+	}
+
+	if err = ownerDriver.DestroyByOwner(authId, keepId); err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.Id != keepId {
+			m.destroyed(info.Id)
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) trackActivity(data *Data, currentTime time.Time) {
+	if data.Owner() == "" {
+		return
+	}
+
+	meta := data.Metadata()
+	if currentTime.Sub(meta.LastActivity) < lastActivityInterval {
+		return
+	}
+
+	meta.LastActivity = currentTime
+	data.setMetadata(meta)
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type payload struct {
+	Id        string            `json:"id"`
+	Data      map[string]string `json:"data"`
+	Encodings map[string]string `json:"encodings,omitempty"`
+	Expire    time.Time         `json:"expire"`
+	Created   time.Time         `json:"created"`
+}
+
+func Marshal(data *Data) ([]byte, error) {
+	return json.Marshal(payload{
+		Id:        data.id,
+		Data:      data.data,
+		Encodings: data.Encodings(),
+		Expire:    data.expireTime,
+		Created:   data.createdTime,
+	})
+}
+
+func Unmarshal(b []byte) (*Data, error) {
+	p := payload{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+
+	data := RestoreData(p.Id, p.Data, p.Expire, p.Created)
+	data.RestoreEncodings(p.Encodings)
+
+	return data, nil
+}
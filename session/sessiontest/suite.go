@@ -0,0 +1,392 @@
+package sessiontest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/censoredgit/light/session"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Harness struct {
+	Driver session.Driver
+	// CollectGarbage runs one garbage collection pass. GC checks are skipped when nil.
+	CollectGarbage func()
+	// Corrupt damages the stored data of the session. Corrupt data checks are skipped when nil.
+	Corrupt func(id string)
+	// Conflict is returned by Close of a driver which rejects stale writes
+	// instead of locking. Concurrent updates are retried on it.
+	Conflict error
+}
+
+// Run checks that the driver behaves the way session.Manager expects.
+// newHarness is called for every subtest and must return a driver that is
+// not initialized yet.
+func Run(t *testing.T, newHarness func(t *testing.T) *Harness) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, h *Harness)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"Modification", testModification},
+		{"Detached", testDetached},
+		{"Encodings", testEncodings},
+		{"Destroy", testDestroy},
+		{"Expiry", testExpiry},
+		{"Concurrent", testConcurrent},
+		{"Corrupt", testCorrupt},
+		{"Touch", testTouch},
+		{"Owner", testOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			if err := h.Driver.Init(); err != nil {
+				t.Fatal(err)
+			}
+			tt.fn(t, h)
+		})
+	}
+}
+
+func open(t *testing.T, h *Harness, id string) *session.Data {
+	t.Helper()
+
+	data, err := h.Driver.Open(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Id() != id {
+		t.Fatalf("opened session id should be %q, got %q", id, data.Id())
+	}
+
+	return data
+}
+
+func closeData(t *testing.T, h *Harness, data *session.Data) {
+	t.Helper()
+
+	if err := h.Driver.Close(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func store(t *testing.T, h *Harness, id string, values map[string]string) {
+	t.Helper()
+
+	data := open(t, h, id)
+	for k, v := range values {
+		data.Set(k, v)
+	}
+	closeData(t, h, data)
+}
+
+func testRoundTrip(t *testing.T, h *Harness) {
+	data := open(t, h, "round-trip")
+	if !data.IsNew() {
+		t.Error("unknown session should be new")
+	}
+	if !data.IsEmpty() {
+		t.Error("new session should be empty")
+	}
+
+	data.Set("a", "1")
+	data.Set("b", "")
+	expire, created := data.Expire(), data.Created()
+	closeData(t, h, data)
+
+	data = open(t, h, "round-trip")
+	defer closeData(t, h, data)
+
+	if data.IsNew() {
+		t.Error("stored session should not be new")
+	}
+	if data.IsModified() {
+		t.Error("opened session should not be modified")
+	}
+	if data.Get("a") != "1" || !data.Has("b") || len(data.All()) != 2 {
+		t.Errorf("values should be restored, got %v", data.All())
+	}
+	if !data.Expire().Truncate(time.Millisecond).Equal(expire.Truncate(time.Millisecond)) {
+		t.Errorf("expire should be restored, got %v want %v", data.Expire(), expire)
+	}
+	if !data.Created().Truncate(time.Millisecond).Equal(created.Truncate(time.Millisecond)) {
+		t.Errorf("created should be restored, got %v want %v", data.Created(), created)
+	}
+}
+
+func testModification(t *testing.T, h *Harness) {
+	store(t, h, "modification", map[string]string{"a": "1", "b": "2"})
+
+	data := open(t, h, "modification")
+	data.Delete("a")
+	data.Set("b", "3")
+	if !data.IsModified() {
+		t.Error("changed session should be modified")
+	}
+	closeData(t, h, data)
+
+	data = open(t, h, "modification")
+	defer closeData(t, h, data)
+
+	if data.Has("a") || data.Get("b") != "3" {
+		t.Errorf("changes should be stored, got %v", data.All())
+	}
+}
+
+func testDetached(t *testing.T, h *Harness) {
+	store(t, h, "detached", map[string]string{"a": "1"})
+
+	data := open(t, h, "detached")
+	closeData(t, h, data)
+	data.Set("a", "changed after close")
+
+	data = open(t, h, "detached")
+	defer closeData(t, h, data)
+
+	if data.Get("a") != "1" {
+		t.Errorf("changes after close should not be stored, got %q", data.Get("a"))
+	}
+}
+
+func testEncodings(t *testing.T, h *Harness) {
+	type value struct {
+		Name  string
+		Items []int
+	}
+
+	data := open(t, h, "encodings")
+	if err := session.SetAs(data, "value", value{Name: "test", Items: []int{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	closeData(t, h, data)
+
+	data = open(t, h, "encodings")
+	defer closeData(t, h, data)
+
+	got, err := session.GetAs[value](data, "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "test" || len(got.Items) != 2 {
+		t.Errorf("encoded value should be restored, got %+v", got)
+	}
+}
+
+func testDestroy(t *testing.T, h *Harness) {
+	destroyer, ok := h.Driver.(session.Destroyer)
+	if !ok {
+		t.Skip("driver does not implement session.Destroyer")
+	}
+
+	store(t, h, "destroy", map[string]string{"a": "1"})
+
+	if err := destroyer.Destroy("destroy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := destroyer.Destroy("destroy"); err != nil {
+		t.Errorf("destroying missing session should not fail: %v", err)
+	}
+
+	data := open(t, h, "destroy")
+	defer closeData(t, h, data)
+
+	if !data.IsNew() || !data.IsEmpty() {
+		t.Error("destroyed session should be new")
+	}
+}
+
+func testExpiry(t *testing.T, h *Harness) {
+	if h.CollectGarbage == nil {
+		t.Skip("harness does not collect garbage")
+	}
+
+	store(t, h, "active", map[string]string{"a": "1"})
+
+	data := open(t, h, "expired")
+	expired := session.RestoreData("expired", map[string]string{"a": "1"}, time.Now().Add(-time.Second), data.Created())
+	expired.Touch()
+	closeData(t, h, expired)
+
+	data = open(t, h, "expired")
+	if data.Expire().After(time.Now()) {
+		t.Error("stored expire should be kept until garbage collection")
+	}
+	closeData(t, h, data)
+
+	h.CollectGarbage()
+
+	data = open(t, h, "expired")
+	if !data.IsNew() {
+		t.Error("expired session should be collected")
+	}
+	data.OnSaved()
+	closeData(t, h, data)
+
+	data = open(t, h, "active")
+	defer closeData(t, h, data)
+	if data.IsNew() || data.Get("a") != "1" {
+		t.Error("active session should not be collected")
+	}
+}
+
+func testConcurrent(t *testing.T, h *Harness) {
+	const workers = 3
+	const iterations = 3
+
+	wg := sync.WaitGroup{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range iterations {
+				err := increment(h.Driver, "concurrent")
+				for h.Conflict != nil && errors.Is(err, h.Conflict) {
+					err = increment(h.Driver, "concurrent")
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	data := open(t, h, "concurrent")
+	defer closeData(t, h, data)
+
+	if counter, err := data.GetInt("counter"); err != nil || counter != workers*iterations {
+		t.Errorf("counter should be %d, got %d (%v)", workers*iterations, counter, err)
+	}
+}
+
+func increment(driver session.Driver, id string) error {
+	var data *session.Data
+	var err error
+
+	for range 5 {
+		if data, err = driver.Open(id); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	value := 0
+	if data.Has("counter") {
+		if value, err = data.GetInt("counter"); err != nil {
+			_ = driver.Close(data)
+			return err
+		}
+	}
+
+	time.Sleep(time.Millisecond)
+	data.SetInt("counter", value+1)
+
+	return driver.Close(data)
+}
+
+func testCorrupt(t *testing.T, h *Harness) {
+	if h.Corrupt == nil {
+		t.Skip("harness can not corrupt data")
+	}
+
+	store(t, h, "corrupt", map[string]string{"a": "1"})
+	h.Corrupt("corrupt")
+
+	data, err := h.Driver.Open("corrupt")
+	if err != nil {
+		t.Fatalf("corrupt session should be replaced, got error: %v", err)
+	}
+	if !data.IsEmpty() {
+		t.Errorf("corrupt session should be empty, got %v", data.All())
+	}
+	data.Set("b", "2")
+	closeData(t, h, data)
+
+	data = open(t, h, "corrupt")
+	defer closeData(t, h, data)
+	if data.Get("b") != "2" {
+		t.Error("replaced session should be stored")
+	}
+}
+
+func testTouch(t *testing.T, h *Harness) {
+	toucher, ok := h.Driver.(session.Toucher)
+	if !ok {
+		t.Skip("driver does not implement session.Toucher")
+	}
+
+	data := open(t, h, "touch")
+	soon := session.RestoreData("touch", map[string]string{"a": "1"}, time.Now().Add(time.Second), data.Created())
+	soon.Touch()
+	closeData(t, h, soon)
+
+	if err := toucher.Touch("touch"); err != nil {
+		t.Fatal(err)
+	}
+
+	data = open(t, h, "touch")
+	defer closeData(t, h, data)
+	if !data.Expire().After(time.Now().Add(time.Second * 2)) {
+		t.Error("touched session expire should be extended")
+	}
+	if data.Get("a") != "1" {
+		t.Error("touched session values should be kept")
+	}
+
+	if err := toucher.Touch("touch-missing"); err == nil {
+		t.Error("touching missing session should fail")
+	}
+}
+
+func testOwner(t *testing.T, h *Harness) {
+	ownerDriver, ok := h.Driver.(session.OwnerDriver)
+	if !ok {
+		t.Skip("driver does not implement session.OwnerDriver")
+	}
+
+	for i, owner := range []string{"john", "john", "john", "jane"} {
+		data := open(t, h, fmt.Sprintf("owner-%d", i))
+		data.SetOwner(owner, session.Metadata{IP: "127.0.0.1", UserAgent: "sessiontest", LastActivity: time.Now()})
+		closeData(t, h, data)
+	}
+
+	infos, err := ownerDriver.SessionsByOwner("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(infos))
+	}
+	for _, info := range infos {
+		if info.Owner != "john" || info.Metadata.UserAgent != "sessiontest" {
+			t.Errorf("unexpected session info %+v", info)
+		}
+	}
+
+	if err = ownerDriver.DestroyByOwner("john", "owner-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err = ownerDriver.SessionsByOwner("john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.Id)
+	}
+	if !slices.Equal(ids, []string{"owner-0"}) {
+		t.Errorf("only kept session should remain, got %v", ids)
+	}
+
+	if infos, err = ownerDriver.SessionsByOwner("jane"); err != nil || len(infos) != 1 {
+		t.Errorf("other owner sessions should remain, got %v (%v)", infos, err)
+	}
+}
@@ -0,0 +1,183 @@
+package session
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Hooks are called synchronously on the request path, so they should be
+// fast and must not use the manager.
+type Hooks struct {
+	OnCreated     func(id string)
+	OnLoaded      func(id string)
+	OnSaved       func(id string)
+	OnRegenerated func(oldId, newId string)
+	OnExpired     func(id string)
+	OnCollected   func(count int)
+	OnDestroyed   func(id string)
+}
+
+type Stats struct {
+	Created     uint64
+	Loaded      uint64
+	Saved       uint64
+	Regenerated uint64
+	Expired     uint64
+	Collected   uint64
+	Destroyed   uint64
+
+	Opens       uint64
+	OpenTime    time.Duration
+	MaxOpenTime time.Duration
+
+	LockWaits       uint64
+	LockWaitTime    time.Duration
+	MaxLockWaitTime time.Duration
+}
+
+func (s Stats) AvgOpenTime() time.Duration {
+	if s.Opens == 0 {
+		return 0
+	}
+
+	return s.OpenTime / time.Duration(s.Opens)
+}
+
+func (s Stats) AvgLockWaitTime() time.Duration {
+	if s.LockWaits == 0 {
+		return 0
+	}
+
+	return s.LockWaitTime / time.Duration(s.LockWaits)
+}
+
+// ObservableDriver is implemented by drivers which lock sessions or collect
+// garbage themselves and can report it to the manager.
+type ObservableDriver interface {
+	Observe(events *DriverEvents)
+}
+
+// DriverEvents is passed to ObservableDriver before Init. A nil
+// *DriverEvents ignores all reports.
+type DriverEvents struct {
+	manager *Manager
+}
+
+func (e *DriverEvents) LockWaited(wait time.Duration) {
+	if e == nil {
+		return
+	}
+
+	s := &e.manager.stats
+	s.lockWaits.Add(1)
+	s.lockWaitTime.Add(int64(wait))
+	storeMax(&s.maxLockWaitTime, int64(wait))
+}
+
+func (e *DriverEvents) Collected(count int) {
+	if e == nil || count <= 0 {
+		return
+	}
+
+	e.manager.stats.collected.Add(uint64(count))
+	if e.manager.hooks.OnCollected != nil {
+		e.manager.hooks.OnCollected(count)
+	}
+}
+
+type stats struct {
+	created     atomic.Uint64
+	loaded      atomic.Uint64
+	saved       atomic.Uint64
+	regenerated atomic.Uint64
+	expired     atomic.Uint64
+	collected   atomic.Uint64
+	destroyed   atomic.Uint64
+
+	opens       atomic.Uint64
+	openTime    atomic.Int64
+	maxOpenTime atomic.Int64
+
+	lockWaits       atomic.Uint64
+	lockWaitTime    atomic.Int64
+	maxLockWaitTime atomic.Int64
+}
+
+func (m *Manager) Stats() Stats {
+	s := &m.stats
+
+	return Stats{
+		Created:         s.created.Load(),
+		Loaded:          s.loaded.Load(),
+		Saved:           s.saved.Load(),
+		Regenerated:     s.regenerated.Load(),
+		Expired:         s.expired.Load(),
+		Collected:       s.collected.Load(),
+		Destroyed:       s.destroyed.Load(),
+		Opens:           s.opens.Load(),
+		OpenTime:        time.Duration(s.openTime.Load()),
+		MaxOpenTime:     time.Duration(s.maxOpenTime.Load()),
+		LockWaits:       s.lockWaits.Load(),
+		LockWaitTime:    time.Duration(s.lockWaitTime.Load()),
+		MaxLockWaitTime: time.Duration(s.maxLockWaitTime.Load()),
+	}
+}
+
+func (m *Manager) opened(start time.Time) {
+	elapsed := int64(time.Since(start))
+
+	m.stats.opens.Add(1)
+	m.stats.openTime.Add(elapsed)
+	storeMax(&m.stats.maxOpenTime, elapsed)
+}
+
+func (m *Manager) created(id string) {
+	m.stats.created.Add(1)
+	if m.hooks.OnCreated != nil {
+		m.hooks.OnCreated(id)
+	}
+}
+
+func (m *Manager) loaded(id string) {
+	m.stats.loaded.Add(1)
+	if m.hooks.OnLoaded != nil {
+		m.hooks.OnLoaded(id)
+	}
+}
+
+func (m *Manager) saved(id string) {
+	m.stats.saved.Add(1)
+	if m.hooks.OnSaved != nil {
+		m.hooks.OnSaved(id)
+	}
+}
+
+func (m *Manager) regenerated(oldId, newId string) {
+	m.stats.regenerated.Add(1)
+	if m.hooks.OnRegenerated != nil {
+		m.hooks.OnRegenerated(oldId, newId)
+	}
+}
+
+func (m *Manager) expired(id string) {
+	m.stats.expired.Add(1)
+	if m.hooks.OnExpired != nil {
+		m.hooks.OnExpired(id)
+	}
+}
+
+func (m *Manager) destroyed(id string) {
+	m.stats.destroyed.Add(1)
+	if m.hooks.OnDestroyed != nil {
+		m.hooks.OnDestroyed(id)
+	}
+}
+
+func storeMax(v *atomic.Int64, n int64) {
+	for {
+		current := v.Load()
+		if n <= current || v.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
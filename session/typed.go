@@ -0,0 +1,167 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func (d *Data) GetInt(key string) (int, error) {
+	return strconv.Atoi(d.Get(key))
+}
+
+func (d *Data) SetInt(key string, value int) {
+	d.Set(key, strconv.Itoa(value))
+}
+
+func (d *Data) GetBool(key string) (bool, error) {
+	return strconv.ParseBool(d.Get(key))
+}
+
+func (d *Data) SetBool(key string, value bool) {
+	d.Set(key, strconv.FormatBool(value))
+}
+
+func (d *Data) GetTime(key string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, d.Get(key))
+}
+
+func (d *Data) SetTime(key string, value time.Time) {
+	d.Set(key, value.Format(time.RFC3339Nano))
+}
+
+func (d *Data) Increment(key string, delta int) (int, error) {
+	value := 0
+	if d.Has(key) {
+		var err error
+		if value, err = d.GetInt(key); err != nil {
+			return 0, err
+		}
+	}
+
+	value += delta
+	d.SetInt(key, value)
+
+	return value, nil
+}
+
+func (d *Data) Pull(key string) string {
+	value := d.Get(key)
+	if d.Has(key) {
+		d.Delete(key)
+	}
+
+	return value
+}
+
+func (d *Data) Remember(key string, fn func() (string, error)) (string, error) {
+	if d.Has(key) {
+		return d.Get(key), nil
+	}
+
+	value, err := fn()
+	if err != nil {
+		return "", err
+	}
+
+	d.Set(key, value)
+
+	return value, nil
+}
+
+func (d *Data) Encoding(key string) string {
+	return d.encodings[key]
+}
+
+func (d *Data) Encodings() map[string]string {
+	encodings := make(map[string]string, len(d.encodings))
+	for k, v := range d.encodings {
+		if _, ok := d.data[k]; ok {
+			encodings[k] = v
+		}
+	}
+
+	return encodings
+}
+
+func (d *Data) RestoreEncodings(encodings map[string]string) {
+	for k, v := range encodings {
+		if _, ok := d.data[k]; ok {
+			d.setEncoding(k, v)
+		}
+	}
+}
+
+func (d *Data) setEncoding(key, name string) {
+	if d.encodings == nil {
+		d.encodings = make(map[string]string)
+	}
+
+	d.encodings[key] = name
+}
+
+func (d *Data) Codec() Codec {
+	if d.codec == nil {
+		return JSONCodec
+	}
+
+	return d.codec
+}
+
+func GetAs[T any](d *Data, key string) (T, error) {
+	var value T
+
+	if !d.Has(key) {
+		return value, fmt.Errorf("%w: %s", ErrNotExists, key)
+	}
+
+	codec := d.Codec()
+	if name := d.Encoding(key); name != "" {
+		var err error
+		if codec, err = LookupCodec(name); err != nil {
+			return value, err
+		}
+	}
+
+	err := codec.Decode(d.Get(key), &value)
+
+	return value, err
+}
+
+func SetAs[T any](d *Data, key string, value T) error {
+	codec := d.Codec()
+
+	encoded, err := codec.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	d.Set(key, encoded)
+	d.setEncoding(key, codec.Name())
+
+	return nil
+}
+
+func PullAs[T any](d *Data, key string) (T, error) {
+	value, err := GetAs[T](d, key)
+	if err != nil {
+		return value, err
+	}
+
+	d.Delete(key)
+
+	return value, nil
+}
+
+func RememberAs[T any](d *Data, key string, fn func() (T, error)) (T, error) {
+	if d.Has(key) {
+		return GetAs[T](d, key)
+	}
+
+	value, err := fn()
+	if err != nil {
+		return value, err
+	}
+
+	return value, SetAs(d, key, value)
+}
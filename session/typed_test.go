@@ -0,0 +1,108 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+type typedTestValue struct {
+	Name  string
+	Items []int
+}
+
+func TestDataTypedAccessors(t *testing.T) {
+	d := NewData("1", time.Minute)
+
+	d.SetInt("int", 42)
+	if v, err := d.GetInt("int"); err != nil || v != 42 {
+		t.Errorf("GetInt failed: %v %v", v, err)
+	}
+
+	d.SetBool("bool", true)
+	if v, err := d.GetBool("bool"); err != nil || !v {
+		t.Errorf("GetBool failed: %v %v", v, err)
+	}
+
+	now := time.Now()
+	d.SetTime("time", now)
+	if v, err := d.GetTime("time"); err != nil || !v.Equal(now) {
+		t.Errorf("GetTime failed: %v %v", v, err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if v, err := d.Increment("counter", 1); err != nil || v != i {
+			t.Fatalf("Increment failed: %v %v", v, err)
+		}
+	}
+
+	d.Set("text", "x")
+	if _, err := d.Increment("text", 1); err == nil {
+		t.Error("Increment should fail on non-numeric value")
+	}
+
+	if d.Pull("counter") != "3" || d.Has("counter") {
+		t.Error("Pull should return and delete value")
+	}
+
+	calls := 0
+	fn := func() (string, error) {
+		calls++
+		return "computed", nil
+	}
+	for range 2 {
+		if v, err := d.Remember("remember", fn); err != nil || v != "computed" {
+			t.Fatalf("Remember failed: %v %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Remember should compute once, computed %d times", calls)
+	}
+}
+
+func TestDataGenericAccessors(t *testing.T) {
+	value := typedTestValue{Name: "test", Items: []int{1, 2, 3}}
+
+	for _, codec := range []Codec{JSONCodec, GobCodec} {
+		d := NewData("1", time.Minute)
+		d.codec = codec
+
+		if err := SetAs(d, "value", value); err != nil {
+			t.Fatal(err)
+		}
+		if d.Encoding("value") != codec.Name() {
+			t.Errorf("encoding should be %s, got %s", codec.Name(), d.Encoding("value"))
+		}
+
+		b, err := Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored, err := Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := GetAs[typedTestValue](restored, "value")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != value.Name || len(got.Items) != len(value.Items) {
+			t.Errorf("%s codec restored %+v", codec.Name(), got)
+		}
+
+		if _, err = PullAs[typedTestValue](restored, "value"); err != nil || restored.Has("value") {
+			t.Error("PullAs should delete value")
+		}
+
+		if _, err = GetAs[typedTestValue](restored, "value"); err == nil {
+			t.Error("GetAs should fail on missing value")
+		}
+	}
+
+	d := NewData("1", time.Minute)
+	d.Set("value", "plain")
+	if d.Encoding("value") != "" {
+		t.Error("Set should reset value encoding")
+	}
+}